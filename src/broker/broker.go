@@ -0,0 +1,38 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrClosed is returned by brokers that were closed.
+	ErrClosed = errors.New("broker: closed")
+	// ErrCorruptMessage is returned by Receive for a message that could not be
+	// decoded. The message is moved to the topic's dead letters.
+	ErrCorruptMessage = errors.New("broker: corrupt message")
+)
+
+// Message is an asynchronous service call. It carries the same method, URI,
+// headers (including the Access-Key) and JSON body as the HTTP request it replaces.
+type Message struct {
+	ID          string            `json:"id"`
+	Topic       string            `json:"topic"`
+	Method      string            `json:"method"`
+	URI         string            `json:"uri"`
+	Headers     map[string]string `json:"headers"`
+	Body        json.RawMessage   `json:"body,omitempty"`
+	PublishedAt time.Time         `json:"published_at"`
+}
+
+// Broker transports messages between services.
+type Broker interface {
+	// Publish enqueues msg on topic.
+	Publish(ctx context.Context, topic string, msg Message) error
+	// Receive blocks until a message is available on topic or ctx is done.
+	Receive(ctx context.Context, topic string) (Message, error)
+	// Close releases the broker resources.
+	Close() error
+}
@@ -0,0 +1,176 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/SIM-MBKM/mod-service/src/redistest"
+)
+
+// testBrokers returns a fresh broker of every kind; inject stores a raw,
+// undecodable payload on a topic, or is nil when the broker has no encoding.
+func testBrokers(t *testing.T) map[string]func(t *testing.T) (b Broker, inject func(topic, payload string)) {
+	return map[string]func(t *testing.T) (Broker, func(string, string)){
+		"memory": func(t *testing.T) (Broker, func(string, string)) {
+			return NewMemoryBroker(), nil
+		},
+		"file": func(t *testing.T) (Broker, func(string, string)) {
+			dir := t.TempDir()
+			b, err := NewFileBroker(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.PollInterval = 10 * time.Millisecond
+			return b, func(topic, payload string) {
+				os.MkdirAll(filepath.Join(dir, topic), 0o755)
+				if err := os.WriteFile(filepath.Join(dir, topic, "0-corrupt.json"), []byte(payload), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		},
+		"redis": func(t *testing.T) (Broker, func(string, string)) {
+			server := redistest.NewServer()
+			t.Cleanup(server.Close)
+			b := NewRedisBroker(helpers.NewRedisClient(server.Addr), "mbkm:")
+			return b, func(topic, payload string) { server.Push("mbkm:"+topic, payload) }
+		},
+	}
+}
+
+func TestBrokerPublishReceive(t *testing.T) {
+	for name, newBroker := range testBrokers(t) {
+		t.Run(name, func(t *testing.T) {
+			b, _ := newBroker(t)
+			defer b.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			for _, id := range []string{"1", "2", "3"} {
+				msg := Message{
+					ID:      id,
+					Method:  "POST",
+					URI:     "students/" + id,
+					Headers: map[string]string{"Access-Key": "key"},
+					Body:    json.RawMessage(`{"nim":"` + id + `"}`),
+				}
+				if err := b.Publish(ctx, "registration", msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, id := range []string{"1", "2", "3"} {
+				msg, err := b.Receive(ctx, "registration")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if msg.ID != id || msg.Topic != "registration" || msg.URI != "students/"+id || msg.Headers["Access-Key"] != "key" || string(msg.Body) != `{"nim":"`+id+`"}` {
+					t.Fatalf("received %+v, want message %s", msg, id)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerReceiveContext(t *testing.T) {
+	for name, newBroker := range testBrokers(t) {
+		t.Run(name, func(t *testing.T) {
+			b, _ := newBroker(t)
+			defer b.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if _, err := b.Receive(ctx, "empty"); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Receive() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	for name, newBroker := range testBrokers(t) {
+		t.Run(name, func(t *testing.T) {
+			b, _ := newBroker(t)
+			done := make(chan error, 1)
+			go func() {
+				_, err := b.Receive(context.Background(), "registration")
+				done <- err
+			}()
+			time.Sleep(50 * time.Millisecond)
+			b.Close()
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, ErrClosed) {
+					t.Fatalf("Receive() error = %v, want %v", err, ErrClosed)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("Close did not stop Receive")
+			}
+			if err := b.Publish(context.Background(), "registration", Message{ID: "1"}); !errors.Is(err, ErrClosed) {
+				t.Fatalf("Publish() after Close error = %v, want %v", err, ErrClosed)
+			}
+		})
+	}
+}
+
+func TestBrokerCorruptMessage(t *testing.T) {
+	for name, newBroker := range testBrokers(t) {
+		t.Run(name, func(t *testing.T) {
+			b, inject := newBroker(t)
+			if inject == nil {
+				t.Skip("messages are not encoded")
+			}
+			defer b.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			inject("registration", "{not json")
+			if _, err := b.Receive(ctx, "registration"); !errors.Is(err, ErrCorruptMessage) {
+				t.Fatalf("Receive() error = %v, want %v", err, ErrCorruptMessage)
+			}
+			// The consumer moves on to the next message.
+			if err := b.Publish(ctx, "registration", Message{ID: "next"}); err != nil {
+				t.Fatal(err)
+			}
+			if msg, err := b.Receive(ctx, "registration"); err != nil || msg.ID != "next" {
+				t.Fatalf("Receive() = %+v, %v, want message next", msg, err)
+			}
+		})
+	}
+}
+
+func TestRedisBrokerDeadLetters(t *testing.T) {
+	server := redistest.NewServer()
+	defer server.Close()
+	b := NewRedisBroker(helpers.NewRedisClient(server.Addr), "mbkm:")
+	defer b.Close()
+
+	server.Push("mbkm:registration", "{not json")
+	if _, err := b.Receive(context.Background(), "registration"); !errors.Is(err, ErrCorruptMessage) {
+		t.Fatalf("Receive() error = %v, want %v", err, ErrCorruptMessage)
+	}
+	if dead := server.List("mbkm:registration:dead"); len(dead) != 1 || dead[0] != "{not json" {
+		t.Fatalf("dead letters = %q", dead)
+	}
+}
+
+func TestFileBrokerDeadLetters(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewFileBroker(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(dir, "registration"), 0o755)
+	os.WriteFile(filepath.Join(dir, "registration", "0-corrupt.json"), []byte("{not json"), 0o644)
+
+	if _, err := b.Receive(context.Background(), "registration"); !errors.Is(err, ErrCorruptMessage) {
+		t.Fatalf("Receive() error = %v, want %v", err, ErrCorruptMessage)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "registration", ".0-corrupt.json.dead")); err != nil {
+		t.Fatalf("dead letter not kept: %v", err)
+	}
+}
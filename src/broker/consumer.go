@@ -0,0 +1,205 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnauthorized means the message Access-Key failed verification.
+	ErrUnauthorized = errors.New("broker: message not authorized")
+	// ErrNoHandler means no handler matches the message method and URI.
+	ErrNoHandler = errors.New("broker: no handler for message")
+)
+
+// HandlerFunc handles one message, like a gin handler handles one request.
+type HandlerFunc func(c *Context) error
+
+// Context is passed to handlers.
+type Context struct {
+	context.Context
+	Message Message
+	Params  map[string]string
+}
+
+// Param returns the value of a ":name" segment of the route.
+func (c *Context) Param(name string) string {
+	return c.Params[name]
+}
+
+// GetHeader returns a message header; names are case-insensitive.
+func (c *Context) GetHeader(key string) string {
+	return messageHeader(c.Message).Get(key)
+}
+
+// BindJSON decodes the message body into v.
+func (c *Context) BindJSON(v interface{}) error {
+	if len(c.Message.Body) == 0 {
+		return errors.New("broker: empty message body")
+	}
+	return json.Unmarshal(c.Message.Body, v)
+}
+
+type route struct {
+	method   string
+	segments []string
+	handler  HandlerFunc
+}
+
+// Consumer receives messages from a broker, verifies their Access-Key and
+// dispatches them to the handler registered for their method and URI.
+type Consumer struct {
+	Broker Broker
+	// Verify checks the Access-Key in the headers of every message, see
+	// middleware.MessageVerifier. Nil accepts every message.
+	Verify func(ctx context.Context, header http.Header) error
+	// OnError is called for messages that could not be received or handled; it
+	// logs by default. For receive errors msg only carries the Topic.
+	OnError func(msg Message, err error)
+
+	routes []route
+}
+
+// NewConsumer creates a Consumer that checks messages with verify, usually
+// built by middleware.MessageVerifier with the options of AccessKeyMiddleware.
+func NewConsumer(b Broker, verify func(ctx context.Context, header http.Header) error) *Consumer {
+	return &Consumer{Broker: b, Verify: verify}
+}
+
+// Handle registers handler for method and a gin style path such as "students/:id".
+func (c *Consumer) Handle(method, path string, handler HandlerFunc) {
+	c.routes = append(c.routes, route{
+		method:   strings.ToUpper(method),
+		segments: splitPath(path),
+		handler:  handler,
+	})
+}
+
+// POST is a shortcut for Handle("POST", path, handler).
+func (c *Consumer) POST(path string, handler HandlerFunc) {
+	c.Handle("POST", path, handler)
+}
+
+// PUT is a shortcut for Handle("PUT", path, handler).
+func (c *Consumer) PUT(path string, handler HandlerFunc) {
+	c.Handle("PUT", path, handler)
+}
+
+// PATCH is a shortcut for Handle("PATCH", path, handler).
+func (c *Consumer) PATCH(path string, handler HandlerFunc) {
+	c.Handle("PATCH", path, handler)
+}
+
+// DELETE is a shortcut for Handle("DELETE", path, handler).
+func (c *Consumer) DELETE(path string, handler HandlerFunc) {
+	c.Handle("DELETE", path, handler)
+}
+
+// GET is a shortcut for Handle("GET", path, handler).
+func (c *Consumer) GET(path string, handler HandlerFunc) {
+	c.Handle("GET", path, handler)
+}
+
+// Dispatch verifies msg and runs its handler.
+func (c *Consumer) Dispatch(ctx context.Context, msg Message) error {
+	if c.Verify != nil {
+		if err := c.Verify(ctx, messageHeader(msg)); err != nil {
+			return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+	}
+
+	path := msg.URI
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	segments := splitPath(path)
+
+	for _, r := range c.routes {
+		if r.method != strings.ToUpper(msg.Method) {
+			continue
+		}
+		if params, ok := matchSegments(r.segments, segments); ok {
+			return r.handler(&Context{Context: ctx, Message: msg, Params: params})
+		}
+	}
+	return fmt.Errorf("%w: %s %s", ErrNoHandler, msg.Method, msg.URI)
+}
+
+// receiveRetryDelay is how long Run waits after Receive failed, e.g. because
+// the connection to the broker was lost.
+const receiveRetryDelay = time.Second
+
+// Run receives and dispatches messages from topic until ctx is done or the broker
+// is closed. Other receive errors, such as corrupt messages, are reported
+// through OnError and the consumer carries on.
+func (c *Consumer) Run(ctx context.Context, topic string) error {
+	for {
+		msg, err := c.Broker.Receive(ctx, topic)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+				return err
+			}
+			c.report(Message{Topic: topic}, err)
+			if errors.Is(err, ErrCorruptMessage) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(receiveRetryDelay):
+			}
+			continue
+		}
+		if err := c.Dispatch(ctx, msg); err != nil {
+			c.report(msg, err)
+		}
+	}
+}
+
+func (c *Consumer) report(msg Message, err error) {
+	if c.OnError != nil {
+		c.OnError(msg, err)
+		return
+	}
+	log.Printf("broker: message %s (%s %s) on %s failed: %v", msg.ID, msg.Method, msg.URI, msg.Topic, err)
+}
+
+// messageHeader returns the headers of msg with canonical names.
+func messageHeader(msg Message) http.Header {
+	header := make(http.Header, len(msg.Headers))
+	for name, value := range msg.Headers {
+		header.Set(name, value)
+	}
+	return header
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func matchSegments(pattern, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, part := range pattern {
+		if strings.HasPrefix(part, ":") {
+			params[part[1:]] = segments[i]
+			continue
+		}
+		if part != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,161 @@
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SIM-MBKM/mod-service/src/broker"
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/SIM-MBKM/mod-service/src/middleware"
+	"github.com/SIM-MBKM/mod-service/src/service"
+)
+
+const (
+	consumerAppKey    = "base64:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	consumerOldKey    = "base64:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+	consumerCallerKey = "base64:Y2FsbGVyLWtleS0wMTIzNDU2Nzg5YWJjZGVmMDEyMzQ="
+)
+
+// publishThrough makes svc call "students/1" asynchronously through a memory
+// broker and returns the published message.
+func publishThrough(t *testing.T, svc *service.Service) broker.Message {
+	t.Helper()
+	b := broker.NewMemoryBroker()
+	svc.Broker, svc.BrokerTopic, svc.AsyncURIs = b, "registration", []string{"students"}
+	if _, err := svc.Request(http.MethodPost, "students/1", map[string]interface{}{"nim": "5025201001"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := b.Receive(context.Background(), "registration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestConsumerVerifiesLikeAccessKeyMiddleware(t *testing.T) {
+	t.Setenv("APP_KEY", consumerAppKey)
+	credentials, err := helpers.NewCallerCredentials(helpers.CallerCredential{ID: "registration", Key: consumerCallerKey, Scopes: []string{"students.write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := helpers.NewKeyring(helpers.AccessKey{Secret: consumerAppKey}, helpers.AccessKey{Secret: consumerOldKey})
+
+	tests := []struct {
+		name    string
+		service func(*service.Service)
+		opts    []middleware.AccessKeyOption
+		wantErr bool
+	}{
+		{"shared key", func(s *service.Service) {}, nil, false},
+		{"v2 key", func(s *service.Service) { s.AccessKeyVersion = helpers.AccessKeyV2; s.Audience = "grades" }, []middleware.AccessKeyOption{middleware.WithAccessKeyV2(middleware.AccessKeyV2Config{Audience: "grades"})}, false},
+		{"v2 key for another audience", func(s *service.Service) { s.AccessKeyVersion = helpers.AccessKeyV2; s.Audience = "finance" }, []middleware.AccessKeyOption{middleware.WithAccessKeyV2(middleware.AccessKeyV2Config{Audience: "grades"})}, true},
+		{"previous keyring key", func(s *service.Service) { s.Keyring = helpers.NewKeyring(helpers.AccessKey{Secret: consumerOldKey}) }, []middleware.AccessKeyOption{middleware.WithKeyring(ring)}, false},
+		{"caller credential", func(s *service.Service) { s.CallerID, s.CallerKey = "registration", consumerCallerKey }, []middleware.AccessKeyOption{middleware.WithCallerCredentials(credentials, true)}, false},
+		{"caller credential not configured", func(s *service.Service) { s.CallerID, s.CallerKey = "registration", consumerCallerKey }, nil, true},
+		{"shared key with callers required", func(s *service.Service) {}, []middleware.AccessKeyOption{middleware.WithCallerCredentials(credentials, true)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewService("http://grades.test", nil)
+			tt.service(svc)
+			msg := publishThrough(t, svc)
+
+			verify, err := middleware.MessageVerifier(consumerAppKey, 60, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			consumer := broker.NewConsumer(nil, verify)
+			handled := false
+			consumer.POST("students/:id", func(c *broker.Context) error {
+				handled = c.Param("id") == "1"
+				return nil
+			})
+
+			err = consumer.Dispatch(context.Background(), msg)
+			if tt.wantErr {
+				if !errors.Is(err, broker.ErrUnauthorized) || handled {
+					t.Fatalf("Dispatch() error = %v, handled = %v, want %v", err, handled, broker.ErrUnauthorized)
+				}
+				return
+			}
+			if err != nil || !handled {
+				t.Fatalf("Dispatch() error = %v, handled = %v", err, handled)
+			}
+		})
+	}
+}
+
+func TestConsumerRejectsBadKeys(t *testing.T) {
+	t.Setenv("APP_KEY", consumerAppKey)
+	msg := publishThrough(t, service.NewService("http://grades.test", nil))
+	verify, err := middleware.MessageVerifier(consumerAppKey, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer := broker.NewConsumer(nil, verify)
+	consumer.POST("students/:id", func(c *broker.Context) error { return nil })
+
+	// Header names are case-insensitive, like HTTP.
+	lower := msg
+	lower.Headers = map[string]string{"access-key": msg.Headers["Access-Key"]}
+	if err := consumer.Dispatch(context.Background(), lower); err != nil {
+		t.Fatalf("Dispatch() with a lowercase header error = %v", err)
+	}
+
+	for name, headers := range map[string]map[string]string{
+		"missing":  {},
+		"tampered": {"Access-Key": msg.Headers["Access-Key"][:len(msg.Headers["Access-Key"])-4] + "AAA="},
+		"other":    {"Access-Key": "not-a-key"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			bad := msg
+			bad.Headers = headers
+			if err := consumer.Dispatch(context.Background(), bad); !errors.Is(err, broker.ErrUnauthorized) {
+				t.Fatalf("Dispatch() error = %v, want %v", err, broker.ErrUnauthorized)
+			}
+		})
+	}
+}
+
+func TestConsumerRun(t *testing.T) {
+	b := broker.NewMemoryBroker()
+	consumer := broker.NewConsumer(b, nil)
+	handled := make(chan string, 2)
+	consumer.PUT("students/:id", func(c *broker.Context) error {
+		handled <- c.Param("id")
+		return nil
+	})
+	var failures []error
+	consumer.OnError = func(msg broker.Message, err error) { failures = append(failures, err) }
+
+	done := make(chan error, 1)
+	go func() { done <- consumer.Run(context.Background(), "registration") }()
+
+	ctx := context.Background()
+	b.Publish(ctx, "registration", broker.Message{ID: "1", Method: "DELETE", URI: "students/1"})
+	b.Publish(ctx, "registration", broker.Message{ID: "2", Method: "PUT", URI: "students/2?notify=1"})
+	select {
+	case id := <-handled:
+		if id != "2" {
+			t.Fatalf("handled student %s, want 2", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not handled")
+	}
+
+	b.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, broker.ErrClosed) {
+			t.Fatalf("Run() error = %v, want %v", err, broker.ErrClosed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not stop after Close")
+	}
+	if len(failures) != 1 || !errors.Is(failures[0], broker.ErrNoHandler) {
+		t.Fatalf("failures = %v, want one %v", failures, broker.ErrNoHandler)
+	}
+}
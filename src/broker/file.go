@@ -0,0 +1,150 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileBroker stores each message as a JSON file under <Dir>/<topic>. Several
+// processes may share the directory: receivers claim a file by renaming it,
+// which is atomic on a single filesystem. Corrupt messages are kept as
+// ".<name>.dead" files.
+type FileBroker struct {
+	Dir string
+	// PollInterval is how often Receive looks for new files; zero uses
+	// DefaultPollInterval.
+	PollInterval time.Duration
+
+	sequence atomic.Uint64
+	closed   atomic.Bool
+}
+
+// DefaultPollInterval is the PollInterval of FileBroker.
+const DefaultPollInterval = 200 * time.Millisecond
+
+// NewFileBroker creates a FileBroker rooted at dir.
+func NewFileBroker(dir string) (*FileBroker, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileBroker{Dir: dir, PollInterval: DefaultPollInterval}, nil
+}
+
+// Publish implements Broker.
+func (f *FileBroker) Publish(ctx context.Context, topic string, msg Message) error {
+	if f.closed.Load() {
+		return ErrClosed
+	}
+
+	dir, err := f.topicDir(topic)
+	if err != nil {
+		return err
+	}
+	msg.Topic = topic
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	// Write under a temporary name first so receivers never see partial files.
+	name := fmt.Sprintf("%020d-%06d-%d.json", time.Now().UnixNano(), f.sequence.Add(1)%1000000, os.Getpid())
+	tmp := filepath.Join(dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, name))
+}
+
+// Receive implements Broker.
+func (f *FileBroker) Receive(ctx context.Context, topic string) (Message, error) {
+	dir, err := f.topicDir(topic)
+	if err != nil {
+		return Message{}, err
+	}
+
+	interval := f.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if f.closed.Load() {
+			return Message{}, ErrClosed
+		}
+
+		msg, found, err := f.claim(dir)
+		if err != nil || found {
+			return msg, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close implements Broker.
+func (f *FileBroker) Close() error {
+	f.closed.Store(true)
+	return nil
+}
+
+func (f *FileBroker) claim(dir string) (Message, bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Message{}, false, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		claimed := filepath.Join(dir, "."+name+".claimed")
+		if err := os.Rename(filepath.Join(dir, name), claimed); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// Another receiver got it first.
+				continue
+			}
+			return Message{}, false, err
+		}
+
+		data, err := os.ReadFile(claimed)
+		if err != nil {
+			os.Remove(claimed)
+			return Message{}, false, err
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			os.Rename(claimed, filepath.Join(dir, "."+name+".dead"))
+			return Message{}, false, fmt.Errorf("%w %s: %v", ErrCorruptMessage, name, err)
+		}
+		os.Remove(claimed)
+		return msg, true, nil
+	}
+	return Message{}, false, nil
+}
+
+func (f *FileBroker) topicDir(topic string) (string, error) {
+	if topic == "" || strings.ContainsAny(topic, `/\`) || topic == "." || topic == ".." {
+		return "", fmt.Errorf("broker: invalid topic %q", topic)
+	}
+	dir := filepath.Join(f.Dir, topic)
+	return dir, os.MkdirAll(dir, 0o755)
+}
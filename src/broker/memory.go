@@ -0,0 +1,95 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker keeps messages in process. It is meant for tests and for services
+// that publish to themselves.
+type MemoryBroker struct {
+	mu     sync.Mutex
+	queues map[string][]Message
+	notify map[string]chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewMemoryBroker creates a new instance of MemoryBroker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		queues: map[string][]Message{},
+		notify: map[string]chan struct{}{},
+		closed: make(chan struct{}),
+	}
+}
+
+// Publish implements Broker.
+func (m *MemoryBroker) Publish(ctx context.Context, topic string, msg Message) error {
+	select {
+	case <-m.closed:
+		return ErrClosed
+	default:
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msg.Topic = topic
+	m.queues[topic] = append(m.queues[topic], msg)
+	m.signal(topic)
+	return nil
+}
+
+// Receive implements Broker.
+func (m *MemoryBroker) Receive(ctx context.Context, topic string) (Message, error) {
+	for {
+		m.mu.Lock()
+		if queue := m.queues[topic]; len(queue) > 0 {
+			msg := queue[0]
+			m.queues[topic] = queue[1:]
+			m.mu.Unlock()
+			return msg, nil
+		}
+		wait := m.channel(topic)
+		m.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-m.closed:
+			return Message{}, ErrClosed
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		}
+	}
+}
+
+// Len returns the number of pending messages on topic.
+func (m *MemoryBroker) Len(topic string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queues[topic])
+}
+
+// Close implements Broker.
+func (m *MemoryBroker) Close() error {
+	m.once.Do(func() { close(m.closed) })
+	return nil
+}
+
+// channel returns the channel closed on the next publish to topic. m.mu must be held.
+func (m *MemoryBroker) channel(topic string) chan struct{} {
+	ch, ok := m.notify[topic]
+	if !ok {
+		ch = make(chan struct{})
+		m.notify[topic] = ch
+	}
+	return ch
+}
+
+// signal wakes the receivers waiting on topic. m.mu must be held.
+func (m *MemoryBroker) signal(topic string) {
+	if ch, ok := m.notify[topic]; ok {
+		close(ch)
+		delete(m.notify, topic)
+	}
+}
@@ -0,0 +1,92 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+)
+
+// RedisBroker uses Redis lists as queues: Publish does LPUSH and Receive does BRPOP
+// on "<Prefix><topic>". Corrupt messages are pushed to "<Prefix><topic>:dead".
+// It works with Redis or any RESP compatible stand-in.
+type RedisBroker struct {
+	Client *helpers.RedisClient
+	Prefix string
+	// WaitSeconds is the BRPOP timeout between checks of the receive context.
+	WaitSeconds int
+
+	closed atomic.Bool
+}
+
+// NewRedisBroker creates a RedisBroker using client.
+func NewRedisBroker(client *helpers.RedisClient, prefix string) *RedisBroker {
+	return &RedisBroker{Client: client, Prefix: prefix, WaitSeconds: 1}
+}
+
+// Publish implements Broker.
+func (r *RedisBroker) Publish(ctx context.Context, topic string, msg Message) error {
+	if r.closed.Load() {
+		return ErrClosed
+	}
+	msg.Topic = topic
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = r.Client.Do(ctx, "LPUSH", r.Prefix+topic, string(data))
+	return err
+}
+
+// Receive implements Broker.
+func (r *RedisBroker) Receive(ctx context.Context, topic string) (Message, error) {
+	wait := r.WaitSeconds
+	if wait <= 0 {
+		wait = 1
+	}
+
+	for {
+		if r.closed.Load() {
+			return Message{}, ErrClosed
+		}
+		reply, err := r.Client.Blocking(ctx, "BRPOP", r.Prefix+topic, fmt.Sprint(wait))
+		if err != nil {
+			// Close interrupts the running BRPOP.
+			if r.closed.Load() {
+				return Message{}, ErrClosed
+			}
+			return Message{}, err
+		}
+		if reply == nil {
+			// Timed out without a message, check ctx and wait again.
+			if err := ctx.Err(); err != nil {
+				return Message{}, err
+			}
+			continue
+		}
+
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 2 {
+			return Message{}, errors.New("broker: unexpected BRPOP reply")
+		}
+		payload, _ := items[1].(string)
+
+		var msg Message
+		if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+			if _, deadErr := r.Client.Do(ctx, "LPUSH", r.Prefix+topic+":dead", payload); deadErr != nil {
+				return Message{}, fmt.Errorf("%w: %v (dead letter not stored: %v)", ErrCorruptMessage, err, deadErr)
+			}
+			return Message{}, fmt.Errorf("%w: %v", ErrCorruptMessage, err)
+		}
+		return msg, nil
+	}
+}
+
+// Close implements Broker.
+func (r *RedisBroker) Close() error {
+	r.closed.Store(true)
+	return r.Client.Close()
+}
@@ -0,0 +1,112 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Access-Key plaintext formats, detected from the decrypted value.
+const (
+	// AccessKeyLegacy is "<secret>@<timestamp>", the format Laravel services send.
+	AccessKeyLegacy = 0
+	// AccessKeyNonce is "1:<secret>@<timestamp>@<nonce>", which can be used only once.
+	AccessKeyNonce = 1
+	// AccessKeyV2 is an AEAD encrypted JSON document with issuer, audience,
+	// expiry and scopes, see EncryptAccessKeyClaims.
+	AccessKeyV2 = 2
+)
+
+const accessKeyNoncePrefix = "1:"
+
+// AccessKeyClaims is the decrypted content of an Access-Key. Issuer, Audience,
+// ExpiresAt and Scopes are only carried by AccessKeyV2 keys.
+type AccessKeyClaims struct {
+	Version int `json:"v"`
+	// Secret is the key the Access-Key was issued with; v2 keys do not embed it.
+	Secret string `json:"-"`
+	// Timestamp is when the key was issued.
+	Timestamp int64  `json:"iat"`
+	Nonce     string `json:"nonce,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	// Scopes are asserted by whoever made the key. The middleware only trusts
+	// them from callers with their own credential, never for the shared APP_KEY.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// HasScope reports whether the claims grant scope.
+func (c *AccessKeyClaims) HasScope(scope string) bool {
+	for _, granted := range c.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatAccessKey builds the plaintext of an Access-Key. An empty nonce
+// produces the legacy format.
+func FormatAccessKey(secret string, timestamp int64, nonce string) string {
+	if nonce == "" {
+		return fmt.Sprintf("%s@%d", secret, timestamp)
+	}
+	return fmt.Sprintf("%s%s@%d@%s", accessKeyNoncePrefix, secret, timestamp, nonce)
+}
+
+// ParseAccessKey parses a decrypted Access-Key plaintext.
+func ParseAccessKey(plaintext string) (*AccessKeyClaims, error) {
+	claims := &AccessKeyClaims{Version: AccessKeyLegacy}
+	parts := strings.Split(plaintext, "@")
+
+	if rest, found := strings.CutPrefix(plaintext, accessKeyNoncePrefix); found {
+		claims.Version = AccessKeyNonce
+		parts = strings.Split(rest, "@")
+		if len(parts) != 3 || parts[2] == "" {
+			return nil, ErrAccessKeyInvalid
+		}
+		claims.Nonce = parts[2]
+		parts = parts[:2]
+	}
+
+	if len(parts) != 2 {
+		return nil, ErrAccessKeyInvalid
+	}
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrAccessKeyInvalid
+	}
+	claims.Secret = parts[0]
+	claims.Timestamp = timestamp
+	return claims, nil
+}
+
+// NewNonce returns a random hex nonce for single use Access-Keys.
+func NewNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkAccessKeyClaims validates the secret and the validity window. The
+// server's expireSeconds caps the age of every key; v2 keys also expire at their exp.
+func checkAccessKeyClaims(claims *AccessKeyClaims, secretKey string, expireSeconds int64) error {
+	if subtle.ConstantTimeCompare([]byte(claims.Secret), []byte(secretKey)) != 1 {
+		return ErrAccessKeyInvalid
+	}
+	currentTimestamp := time.Now().Unix()
+	if claims.Timestamp > currentTimestamp || currentTimestamp-claims.Timestamp > expireSeconds {
+		return ErrAccessKeyExpired
+	}
+	if claims.Version == AccessKeyV2 && currentTimestamp > claims.ExpiresAt {
+		return ErrAccessKeyExpired
+	}
+	return nil
+}
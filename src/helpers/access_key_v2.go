@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accessKeyV2Context separates the v2 encryption key from other uses of APP_KEY.
+const accessKeyV2Context = "mod-service access key v2:"
+
+// DefaultAccessKeyTTL is how long v2 Access-Keys stay valid unless ACCESS_KEY_TTL says otherwise.
+const DefaultAccessKeyTTL = 60 * time.Second
+
+// accessKeyV2Security returns the AES-256-GCM Security keyed by SHA-256 of secret.
+func accessKeyV2Security(secret string) *Security {
+	key := sha256.Sum256([]byte(accessKeyV2Context + secret))
+	return NewSecurity(HashSHA256, base64.StdEncoding.EncodeToString(key[:]), CipherAES256GCM)
+}
+
+// IsAccessKeyV2 reports whether accessKey is in the v2 format, without decrypting it.
+func IsAccessKeyV2(accessKey string) bool {
+	return strings.HasPrefix(accessKey, aeadPrefix)
+}
+
+// EncryptAccessKeyClaims encrypts claims as a v2 Access-Key for secret. Version
+// is set to AccessKeyV2; Secret is not part of the key.
+func EncryptAccessKeyClaims(secret string, claims AccessKeyClaims) (string, error) {
+	if claims.ExpiresAt == 0 || claims.Timestamp == 0 {
+		return "", fmt.Errorf("%w: iat and exp are required", ErrAccessKeyInvalid)
+	}
+	claims.Version = AccessKeyV2
+	plaintext, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return accessKeyV2Security(secret).EncryptString(string(plaintext))
+}
+
+// DecryptAccessKeyClaims decrypts a v2 Access-Key issued for secret. The claims
+// are not validated; Verify does that.
+func DecryptAccessKeyClaims(secret, accessKey string) (*AccessKeyClaims, error) {
+	plaintext, err := accessKeyV2Security(secret).DecryptString(accessKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessKeyInvalid, err)
+	}
+	claims := &AccessKeyClaims{}
+	if err := json.Unmarshal([]byte(plaintext), claims); err != nil || claims.Version != AccessKeyV2 {
+		return nil, ErrAccessKeyInvalid
+	}
+	claims.Secret = secret
+	return claims, nil
+}
+
+// NewAccessKeyClaims returns v2 claims issued now with a fresh nonce, the
+// issuer from AccessKeyIssuer and the lifetime from AccessKeyTTL.
+func NewAccessKeyClaims(audience string, scopes ...string) (AccessKeyClaims, error) {
+	nonce, err := NewNonce()
+	if err != nil {
+		return AccessKeyClaims{}, err
+	}
+	now := time.Now()
+	return AccessKeyClaims{
+		Version:   AccessKeyV2,
+		Timestamp: now.Unix(),
+		ExpiresAt: now.Add(AccessKeyTTL()).Unix(),
+		Nonce:     nonce,
+		Issuer:    AccessKeyIssuer(),
+		Audience:  audience,
+		Scopes:    scopes,
+	}, nil
+}
+
+// AccessKeyVersion is the Access-Key format to issue, from ACCESS_KEY_VERSION:
+// AccessKeyV2, or 1 (the key@timestamp formats) by default so Laravel services
+// that only read v1 keep working.
+func AccessKeyVersion() int {
+	if GetEnv("ACCESS_KEY_VERSION", "1") == "2" {
+		return AccessKeyV2
+	}
+	return 1
+}
+
+// AccessKeyIssuer names this service in v2 keys: ACCESS_KEY_ISSUER, else APP_NAME.
+func AccessKeyIssuer() string {
+	if issuer := GetEnv("ACCESS_KEY_ISSUER", ""); issuer != "" {
+		return issuer
+	}
+	return GetEnv("APP_NAME", "")
+}
+
+// AccessKeyTTL is the lifetime of issued v2 keys: ACCESS_KEY_TTL seconds, or DefaultAccessKeyTTL.
+func AccessKeyTTL() time.Duration {
+	seconds, err := strconv.Atoi(GetEnv("ACCESS_KEY_TTL", ""))
+	if err != nil || seconds <= 0 {
+		return DefaultAccessKeyTTL
+	}
+	return time.Duration(seconds) * time.Second
+}
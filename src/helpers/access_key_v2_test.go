@@ -0,0 +1,116 @@
+package helpers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// v2TestKey encrypts claims issued now for secret, after edit adjusts them.
+func v2TestKey(t *testing.T, secret string, edit func(*AccessKeyClaims)) string {
+	t.Helper()
+	now := time.Now().Unix()
+	claims := AccessKeyClaims{Timestamp: now, ExpiresAt: now + 60, Nonce: "n1", Issuer: "registration", Audience: "students", Scopes: []string{"students.read"}}
+	if edit != nil {
+		edit(&claims)
+	}
+	key, err := EncryptAccessKeyClaims(secret, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestAccessKeyV2Verify(t *testing.T) {
+	security := NewSecurityAccessKeyWithKey(laravelTestKey)
+	now := time.Now().Unix()
+
+	key := v2TestKey(t, laravelTestKey, nil)
+	if !IsAccessKeyV2(key) {
+		t.Fatalf("IsAccessKeyV2(%q) = false", key)
+	}
+	claims, err := security.Verify(key, laravelTestKey, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &AccessKeyClaims{Version: AccessKeyV2, Secret: laravelTestKey, Timestamp: claims.Timestamp, ExpiresAt: claims.ExpiresAt, Nonce: "n1", Issuer: "registration", Audience: "students", Scopes: []string{"students.read"}}
+	if !reflect.DeepEqual(claims, want) {
+		t.Fatalf("claims = %+v, want %+v", claims, want)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want error
+	}{
+		{"other secret", v2TestKey(t, "base64:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=", nil), ErrAccessKeyInvalid},
+		{"tampered", key[:len(key)-4] + "AAAA", ErrAccessKeyInvalid},
+		{"exp passed", v2TestKey(t, laravelTestKey, func(c *AccessKeyClaims) { c.ExpiresAt = now - 1 }), ErrAccessKeyExpired},
+		{"issued in the future", v2TestKey(t, laravelTestKey, func(c *AccessKeyClaims) { c.Timestamp = now + 30 }), ErrAccessKeyExpired},
+		// The server's window caps every key, however far away exp is.
+		{"older than the window", v2TestKey(t, laravelTestKey, func(c *AccessKeyClaims) { c.Timestamp, c.ExpiresAt = now-120, now+3600 }), ErrAccessKeyExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := security.Verify(tt.key, laravelTestKey, 60); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptAccessKeyClaimsRequiresTimes(t *testing.T) {
+	for _, claims := range []AccessKeyClaims{{Timestamp: 1700000000}, {ExpiresAt: 1700000060}} {
+		if _, err := EncryptAccessKeyClaims(laravelTestKey, claims); !errors.Is(err, ErrAccessKeyInvalid) {
+			t.Fatalf("EncryptAccessKeyClaims(%+v) error = %v", claims, err)
+		}
+	}
+}
+
+func TestNewAccessKeyClaimsFromEnv(t *testing.T) {
+	t.Setenv("APP_NAME", "mbkm-registration")
+	t.Setenv("ACCESS_KEY_ISSUER", "")
+	t.Setenv("ACCESS_KEY_TTL", "")
+
+	claims, err := NewAccessKeyClaims("students", "students.read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Issuer != "mbkm-registration" || claims.Audience != "students" || claims.Nonce == "" || claims.ExpiresAt-claims.Timestamp != 60 {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	t.Setenv("ACCESS_KEY_ISSUER", "registration")
+	t.Setenv("ACCESS_KEY_TTL", "300")
+	claims, err = NewAccessKeyClaims("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Issuer != "registration" || claims.ExpiresAt-claims.Timestamp != 300 {
+		t.Fatalf("claims = %+v", claims)
+	}
+}
+
+func TestGenerateAccessKeyVersion(t *testing.T) {
+	security := NewSecurityAccessKeyWithKey(laravelTestKey)
+
+	t.Setenv("ACCESS_KEY_VERSION", "")
+	key, err := security.GenerateAccessKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsAccessKeyV2(key) {
+		t.Fatal("v1 is the default, for Laravel callers")
+	}
+
+	t.Setenv("ACCESS_KEY_VERSION", "2")
+	key, err = security.GenerateAccessKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := security.Verify(key, laravelTestKey, 60)
+	if err != nil || claims.Version != AccessKeyV2 {
+		t.Fatalf("Verify() = %+v, %v", claims, err)
+	}
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CallerHeader names the calling service whose credential encrypted the Access-Key.
+const CallerHeader = "Access-Caller"
+
+// CallerCredential is the secret issued to one calling service.
+type CallerCredential struct {
+	ID       string   `json:"id" yaml:"id"`
+	Name     string   `json:"name" yaml:"name"`
+	Key      string   `json:"key" yaml:"key"`
+	Scopes   []string `json:"scopes" yaml:"scopes"`
+	Disabled bool     `json:"disabled" yaml:"disabled"`
+}
+
+// CallerCredentials maps caller IDs to their credentials.
+type CallerCredentials struct {
+	callers map[string]CallerCredential
+}
+
+// NewCallerCredentials indexes credentials by ID.
+func NewCallerCredentials(credentials ...CallerCredential) (*CallerCredentials, error) {
+	callers := make(map[string]CallerCredential, len(credentials))
+	for _, credential := range credentials {
+		if credential.ID == "" || credential.Key == "" {
+			return nil, fmt.Errorf("caller credential %q needs an id and a key", credential.ID)
+		}
+		if _, exists := callers[credential.ID]; exists {
+			return nil, fmt.Errorf("duplicate caller credential %q", credential.ID)
+		}
+		callers[credential.ID] = credential
+	}
+	return &CallerCredentials{callers: callers}, nil
+}
+
+// LoadCallerCredentials reads a JSON or YAML file of the form
+// {"callers": [{"id": "registration", "name": "...", "key": "...", "scopes": ["..."]}]}.
+func LoadCallerCredentials(path string) (*CallerCredentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document struct {
+		Callers []CallerCredential `json:"callers" yaml:"callers"`
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	default:
+		err = json.Unmarshal(data, &document)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid caller credentials %s: %v", path, err)
+	}
+	return NewCallerCredentials(document.Callers...)
+}
+
+// Lookup returns the enabled credential of caller id.
+func (c *CallerCredentials) Lookup(id string) (CallerCredential, bool) {
+	credential, ok := c.callers[id]
+	if !ok || credential.Disabled {
+		return CallerCredential{}, false
+	}
+	return credential, true
+}
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"context"
+)
+
+// contextKey namespaces the request scoped values stored by this package.
+type contextKey int
+
+const (
+	userTokenKey contextKey = iota
+	onBehalfOfKey
+	noForwardKey
+	localeKey
+)
+
+// WithUserToken returns a context carrying the inbound user bearer token,
+// which outbound Service calls forward automatically.
+func WithUserToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, userTokenKey, token)
+}
+
+// UserTokenFromContext returns the user token stored by WithUserToken, or "".
+func UserTokenFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	token, _ := ctx.Value(userTokenKey).(string)
+	return token
+}
+
+// WithOnBehalfOf returns a context carrying the identity outbound calls act for.
+func WithOnBehalfOf(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, onBehalfOfKey, identity)
+}
+
+// OnBehalfOfFromContext returns the identity stored by WithOnBehalfOf, or "".
+func OnBehalfOfFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	identity, _ := ctx.Value(onBehalfOfKey).(string)
+	return identity
+}
+
+// WithoutTokenForwarding marks ctx so outbound calls made with it do not forward
+// the user token or on-behalf-of identity.
+func WithoutTokenForwarding(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noForwardKey, true)
+}
+
+// TokenForwardingDisabled reports whether WithoutTokenForwarding was applied to ctx.
+func TokenForwardingDisabled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	disabled, _ := ctx.Value(noForwardKey).(bool)
+	return disabled
+}
+
+// WithLocale returns a context carrying the locale negotiated for the request.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// LocaleFromContext returns the request locale stored by WithLocale, falling back
+// to the process wide default held by the Locale singleton.
+func LocaleFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if locale, _ := ctx.Value(localeKey).(string); locale != "" {
+			return locale
+		}
+	}
+	return GetInstance().GetLocale()
+}
@@ -0,0 +1,232 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// EncryptTag marks struct fields that EncryptFields encrypts: `encrypt:"true"`.
+const EncryptTag = "encrypt"
+
+// ErrEncryptFieldsMarshaler is returned by EncryptFields for a json.Marshaler
+// whose type holds tagged fields: its own encoding would write them in plaintext.
+var ErrEncryptFieldsMarshaler = errors.New("encrypt fields: json.Marshaler holds encrypted fields")
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// EncryptFields prepares v for JSON encoding with every struct field tagged
+// `encrypt:"true"` replaced by its ciphertext from the default Encrypter.
+// Strings are encrypted as they are, other values as their JSON encoding, the
+// way Laravel's encrypted casts store them. Structs holding tagged fields, and
+// the maps and slices around them, come back as generic JSON values; anything
+// else is returned unchanged. Values implementing json.Marshaler are left to
+// encode themselves, unless their type holds tagged fields, which is an error.
+func EncryptFields(v interface{}) (interface{}, error) {
+	result, _, err := encryptFields(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return v, nil
+	}
+	return result, nil
+}
+
+// encryptFields returns a replacement for v, or nil when v needs no change.
+func encryptFields(v reflect.Value) (interface{}, bool, error) {
+	if v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		return encryptFields(v.Elem())
+	}
+	if v.IsValid() && (v.Type().Implements(jsonMarshalerType) || reflect.PointerTo(v.Type()).Implements(jsonMarshalerType)) {
+		if hasEncryptTag(v.Type(), map[reflect.Type]bool{}) {
+			return nil, false, fmt.Errorf("%w: %s", ErrEncryptFieldsMarshaler, v.Type())
+		}
+		return nil, false, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return encryptStruct(v)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && (v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8) {
+			return nil, false, nil
+		}
+		var items []interface{}
+		for i := 0; i < v.Len(); i++ {
+			replaced, changed, err := encryptFields(v.Index(i))
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && items == nil {
+				items = make([]interface{}, v.Len())
+				for j := 0; j < i; j++ {
+					items[j] = v.Index(j).Interface()
+				}
+			}
+			if items != nil {
+				if changed {
+					items[i] = replaced
+				} else {
+					items[i] = v.Index(i).Interface()
+				}
+			}
+		}
+		return items, items != nil, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		var entries map[string]interface{}
+		iter := v.MapRange()
+		for iter.Next() {
+			replaced, changed, err := encryptFields(iter.Value())
+			if err != nil {
+				return nil, false, err
+			}
+			if changed {
+				if entries == nil {
+					entries = make(map[string]interface{}, v.Len())
+				}
+				key, err := jsonMapKey(iter.Key())
+				if err != nil {
+					return nil, false, err
+				}
+				entries[key] = replaced
+			}
+		}
+		if entries == nil {
+			return nil, false, nil
+		}
+		iter = v.MapRange()
+		for iter.Next() {
+			key, err := jsonMapKey(iter.Key())
+			if err != nil {
+				return nil, false, err
+			}
+			if _, ok := entries[key]; !ok {
+				entries[key] = iter.Value().Interface()
+			}
+		}
+		return entries, true, nil
+	}
+	return nil, false, nil
+}
+
+// jsonMapKey names a map key the way encoding/json does.
+func jsonMapKey(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if key.Type().Implements(textMarshalerType) {
+		if key.Kind() == reflect.Pointer && key.IsNil() {
+			return "", nil
+		}
+		text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("encrypt fields: unsupported map key type %s", key.Type())
+}
+
+// hasEncryptTag reports whether values of t can hold fields tagged for
+// encryption. Interfaces are not followed: their dynamic type is unknown.
+func hasEncryptTag(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return hasEncryptTag(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Tag.Get(EncryptTag) == "true" || hasEncryptTag(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encryptStruct rebuilds a struct with tagged fields as its JSON object.
+func encryptStruct(v reflect.Value) (interface{}, bool, error) {
+	replacements := map[string]interface{}{}
+	for _, field := range taggedStructFields(v.Type(), "json") {
+		value, ok := fieldByIndex(v, field.index)
+		if !ok || !value.CanInterface() || (field.omitEmpty && value.IsZero()) {
+			continue
+		}
+
+		if field.tag.Get(EncryptTag) == "true" {
+			if (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) && value.IsNil() {
+				continue
+			}
+			encrypted, err := encryptFieldValue(value)
+			if err != nil {
+				return nil, false, err
+			}
+			replacements[field.name] = encrypted
+			continue
+		}
+
+		replaced, changed, err := encryptFields(value)
+		if err != nil {
+			return nil, false, err
+		}
+		if changed {
+			replacements[field.name] = replaced
+		}
+	}
+	if len(replacements) == 0 {
+		return nil, false, nil
+	}
+
+	// The rest of the struct keeps its regular JSON encoding.
+	encoded, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, false, err
+	}
+	var object map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, false, err
+	}
+	for name, replacement := range replacements {
+		object[name] = replacement
+	}
+	return object, true, nil
+}
+
+func encryptFieldValue(value reflect.Value) (string, error) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.String {
+		return encryptDefault(value.String())
+	}
+	plaintext, err := json.Marshal(value.Interface())
+	if err != nil {
+		return "", err
+	}
+	return encryptDefault(string(plaintext))
+}
@@ -0,0 +1,200 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type encryptTestStudent struct {
+	NIM     string              `json:"nim" encrypt:"true"`
+	Name    string              `json:"name"`
+	Grades  []int               `json:"grades,omitempty" encrypt:"true"`
+	Phone   *string             `json:"phone,omitempty" encrypt:"true"`
+	Advisor *encryptTestAdvisor `json:"advisor,omitempty"`
+}
+
+type encryptTestAdvisor struct {
+	NIP string `json:"nip" encrypt:"true"`
+}
+
+type encryptTestMarshaler struct {
+	NIM string `encrypt:"true"`
+}
+
+func (m encryptTestMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"nim": m.NIM})
+}
+
+type encryptTestPlainMarshaler struct{ Name string }
+
+func (m encryptTestPlainMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Name)
+}
+
+type encryptTestKey struct{ ID int }
+
+func (k encryptTestKey) MarshalText() ([]byte, error) {
+	return []byte("student-" + strings.Repeat("x", k.ID)), nil
+}
+
+func useTestDefaultEncrypter(t *testing.T) *LaravelEncrypter {
+	t.Helper()
+	encrypter := newLaravelTestEncrypter(t, LaravelAES256CBC)
+	SetDefaultEncrypter(encrypter)
+	t.Cleanup(func() { SetDefaultEncrypter(nil) })
+	return encrypter
+}
+
+// encryptFieldsJSON runs EncryptFields and decodes its JSON encoding.
+func encryptFieldsJSON(t *testing.T, v interface{}) interface{} {
+	t.Helper()
+	result, err := EncryptFields(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(encoded), "5025201001") || strings.Contains(string(encoded), "198501") {
+		t.Fatalf("plaintext in %s", encoded)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func decryptField(t *testing.T, encrypter *LaravelEncrypter, value interface{}) string {
+	t.Helper()
+	ciphertext, ok := value.(string)
+	if !ok {
+		t.Fatalf("field = %#v, want a ciphertext", value)
+	}
+	plaintext, err := encrypter.DecryptString(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plaintext
+}
+
+func TestEncryptFieldsStruct(t *testing.T) {
+	encrypter := useTestDefaultEncrypter(t)
+	phone := "0812"
+	student := encryptTestStudent{NIM: "5025201001", Name: "Budi", Grades: []int{90, 85}, Phone: &phone, Advisor: &encryptTestAdvisor{NIP: "198501"}}
+
+	object := encryptFieldsJSON(t, &student).(map[string]interface{})
+	if object["name"] != "Budi" {
+		t.Fatalf("name = %#v", object["name"])
+	}
+	if got := decryptField(t, encrypter, object["nim"]); got != "5025201001" {
+		t.Fatalf("nim = %q", got)
+	}
+	// Non-string values are encrypted as their JSON, like Laravel's encrypted:array.
+	if got := decryptField(t, encrypter, object["grades"]); got != "[90,85]" {
+		t.Fatalf("grades = %q", got)
+	}
+	if got := decryptField(t, encrypter, object["phone"]); got != "0812" {
+		t.Fatalf("phone = %q", got)
+	}
+	if got := decryptField(t, encrypter, object["advisor"].(map[string]interface{})["nip"]); got != "198501" {
+		t.Fatalf("advisor.nip = %q", got)
+	}
+
+	// Empty omitempty fields stay omitted rather than encrypting a zero value.
+	object = encryptFieldsJSON(t, encryptTestStudent{NIM: "5025201001"}).(map[string]interface{})
+	for _, name := range []string{"grades", "phone", "advisor"} {
+		if _, ok := object[name]; ok {
+			t.Fatalf("%s present in %v", name, object)
+		}
+	}
+}
+
+func TestEncryptFieldsContainers(t *testing.T) {
+	encrypter := useTestDefaultEncrypter(t)
+	student := encryptTestAdvisor{NIP: "198501"}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		nip  func(decoded interface{}) interface{}
+	}{
+		{"slice", []encryptTestAdvisor{student}, func(d interface{}) interface{} {
+			return d.([]interface{})[0].(map[string]interface{})["nip"]
+		}},
+		{"array", [1]*encryptTestAdvisor{&student}, func(d interface{}) interface{} {
+			return d.([]interface{})[0].(map[string]interface{})["nip"]
+		}},
+		{"string keys", map[string]encryptTestAdvisor{"a": student}, func(d interface{}) interface{} {
+			return d.(map[string]interface{})["a"].(map[string]interface{})["nip"]
+		}},
+		{"int keys", map[int]encryptTestAdvisor{7: student}, func(d interface{}) interface{} {
+			return d.(map[string]interface{})["7"].(map[string]interface{})["nip"]
+		}},
+		{"uint keys", map[uint8]interface{}{7: student, 8: "plain"}, func(d interface{}) interface{} {
+			if d.(map[string]interface{})["8"] != "plain" {
+				t.Fatalf("untouched entry = %#v", d)
+			}
+			return d.(map[string]interface{})["7"].(map[string]interface{})["nip"]
+		}},
+		{"text marshaler keys", map[encryptTestKey]encryptTestAdvisor{{ID: 2}: student}, func(d interface{}) interface{} {
+			return d.(map[string]interface{})["student-xx"].(map[string]interface{})["nip"]
+		}},
+		{"interface", map[string]interface{}{"list": []interface{}{student}}, func(d interface{}) interface{} {
+			return d.(map[string]interface{})["list"].([]interface{})[0].(map[string]interface{})["nip"]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decryptField(t, encrypter, tt.nip(encryptFieldsJSON(t, tt.v))); got != "198501" {
+				t.Fatalf("nip = %q", got)
+			}
+		})
+	}
+}
+
+func TestEncryptFieldsUnchanged(t *testing.T) {
+	useTestDefaultEncrypter(t)
+	now := time.Unix(1700000000, 0)
+	for _, v := range []interface{}{
+		nil,
+		"plain",
+		map[int]string{1: "a"},
+		[]byte("raw"),
+		json.RawMessage(`{"nim":"5025201001"}`),
+		encryptTestPlainMarshaler{Name: "Budi"},
+		now,
+		struct{ Name string }{"Budi"},
+	} {
+		result, err := EncryptFields(v)
+		if err != nil {
+			t.Fatalf("EncryptFields(%#v) error = %v", v, err)
+		}
+		if !reflect.DeepEqual(result, v) {
+			t.Fatalf("EncryptFields(%#v) = %#v", v, result)
+		}
+	}
+}
+
+func TestEncryptFieldsMarshalerWithTags(t *testing.T) {
+	useTestDefaultEncrypter(t)
+	for name, v := range map[string]interface{}{
+		"value":   encryptTestMarshaler{NIM: "5025201001"},
+		"pointer": &encryptTestMarshaler{NIM: "5025201001"},
+		"nested":  map[string]interface{}{"students": []encryptTestMarshaler{{NIM: "5025201001"}}},
+		"field": struct {
+			Student encryptTestMarshaler `json:"student"`
+		}{encryptTestMarshaler{NIM: "5025201001"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := EncryptFields(v); !errors.Is(err, ErrEncryptFieldsMarshaler) {
+				t.Fatalf("EncryptFields() error = %v, want ErrEncryptFieldsMarshaler", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package helpers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+var (
+	defaultEncrypterMu sync.RWMutex
+	defaultEncrypter   Encrypter
+)
+
+// SetDefaultEncrypter replaces the Encrypter used by EncryptedString,
+// Encrypted and EncryptFields.
+func SetDefaultEncrypter(encrypter Encrypter) {
+	defaultEncrypterMu.Lock()
+	defer defaultEncrypterMu.Unlock()
+	defaultEncrypter = encrypter
+}
+
+// DefaultEncrypter returns the Encrypter set with SetDefaultEncrypter, or a
+// LaravelEncrypter from the environment so values match Laravel's encrypted
+// casts. A failed environment setup is retried on the next call.
+func DefaultEncrypter() (Encrypter, error) {
+	defaultEncrypterMu.RLock()
+	encrypter := defaultEncrypter
+	defaultEncrypterMu.RUnlock()
+	if encrypter != nil {
+		return encrypter, nil
+	}
+
+	defaultEncrypterMu.Lock()
+	defer defaultEncrypterMu.Unlock()
+	if defaultEncrypter == nil {
+		laravel, err := NewLaravelEncrypterFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		defaultEncrypter = laravel
+	}
+	return defaultEncrypter, nil
+}
+
+func encryptDefault(value string) (string, error) {
+	encrypter, err := DefaultEncrypter()
+	if err != nil {
+		return "", err
+	}
+	return encrypter.EncryptString(value)
+}
+
+func decryptDefault(value string) (string, error) {
+	encrypter, err := DefaultEncrypter()
+	if err != nil {
+		return "", err
+	}
+	return encrypter.DecryptString(value)
+}
+
+// scanString reads a ciphertext column; ok is false for NULL.
+func scanString(src interface{}) (value string, ok bool, err error) {
+	switch v := src.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return v, true, nil
+	case []byte:
+		return string(v), true, nil
+	}
+	return "", false, fmt.Errorf("cannot scan %T into an encrypted value", src)
+}
+
+// EncryptedString holds plaintext in memory and ciphertext on the wire and in
+// the database, like Laravel's "encrypted" cast.
+type EncryptedString string
+
+// MarshalJSON encrypts the value into a JSON string.
+func (s EncryptedString) MarshalJSON() ([]byte, error) {
+	encrypted, err := encryptDefault(string(s))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encrypted)
+}
+
+// UnmarshalJSON decrypts a JSON string; null leaves the value empty.
+func (s *EncryptedString) UnmarshalJSON(data []byte) error {
+	var encrypted *string
+	if err := json.Unmarshal(data, &encrypted); err != nil {
+		return err
+	}
+	if encrypted == nil {
+		*s = ""
+		return nil
+	}
+	decrypted, err := decryptDefault(*encrypted)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(decrypted)
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (s EncryptedString) Value() (driver.Value, error) {
+	return encryptDefault(string(s))
+}
+
+// Scan implements sql.Scanner; NULL leaves the value empty.
+func (s *EncryptedString) Scan(src interface{}) error {
+	encrypted, ok, err := scanString(src)
+	if err != nil || !ok {
+		*s = ""
+		return err
+	}
+	decrypted, err := decryptDefault(encrypted)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(decrypted)
+	return nil
+}
+
+// String returns the plaintext.
+func (s EncryptedString) String() string {
+	return string(s)
+}
+
+// Encrypted holds any JSON encodable value that travels encrypted, like
+// Laravel's "encrypted:array" and "encrypted:object" casts: the JSON encoding
+// of Data is encrypted with the default Encrypter.
+type Encrypted[T any] struct {
+	Data T
+}
+
+// NewEncrypted wraps data.
+func NewEncrypted[T any](data T) Encrypted[T] {
+	return Encrypted[T]{Data: data}
+}
+
+func (e Encrypted[T]) encrypt() (string, error) {
+	plaintext, err := json.Marshal(e.Data)
+	if err != nil {
+		return "", err
+	}
+	return encryptDefault(string(plaintext))
+}
+
+func (e *Encrypted[T]) decrypt(encrypted string) error {
+	plaintext, err := decryptDefault(encrypted)
+	if err != nil {
+		return err
+	}
+	var data T
+	if err := json.Unmarshal([]byte(plaintext), &data); err != nil {
+		return fmt.Errorf("%w: %v", ErrDecryptSerialization, err)
+	}
+	e.Data = data
+	return nil
+}
+
+// MarshalJSON encrypts the value into a JSON string.
+func (e Encrypted[T]) MarshalJSON() ([]byte, error) {
+	encrypted, err := e.encrypt()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encrypted)
+}
+
+// UnmarshalJSON decrypts a JSON string; null resets Data to its zero value.
+func (e *Encrypted[T]) UnmarshalJSON(data []byte) error {
+	var encrypted *string
+	if err := json.Unmarshal(data, &encrypted); err != nil {
+		return err
+	}
+	if encrypted == nil {
+		var zero T
+		e.Data = zero
+		return nil
+	}
+	return e.decrypt(*encrypted)
+}
+
+// Value implements driver.Valuer.
+func (e Encrypted[T]) Value() (driver.Value, error) {
+	return e.encrypt()
+}
+
+// Scan implements sql.Scanner; NULL resets Data to its zero value.
+func (e *Encrypted[T]) Scan(src interface{}) error {
+	encrypted, ok, err := scanString(src)
+	if err != nil || !ok {
+		var zero T
+		e.Data = zero
+		return err
+	}
+	return e.decrypt(encrypted)
+}
@@ -0,0 +1,133 @@
+package helpers
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Encrypter encrypts and decrypts string values. Security, SecurityAccessKey
+// and LaravelEncrypter implement it, so callers can pick one from configuration.
+type Encrypter interface {
+	EncryptString(value string) (string, error)
+	DecryptString(value string) (string, error)
+}
+
+// Decryption errors shared by Security and SecurityAccessKey. Errors caused by
+// the encrypted value wrap one of them; configuration errors do not.
+var (
+	// ErrDecryptBase64 means the value is not in the expected encoding.
+	ErrDecryptBase64 = errors.New("decrypt: invalid base64 encoding")
+	// ErrDecryptLength means the ciphertext is empty, too short or not block aligned.
+	ErrDecryptLength = errors.New("decrypt: invalid ciphertext length")
+	// ErrDecryptPadding means the plaintext padding is malformed, usually a wrong key.
+	ErrDecryptPadding = errors.New("decrypt: invalid padding")
+	// ErrDecryptMAC means authenticated ciphertext failed verification.
+	ErrDecryptMAC = errors.New("decrypt: authentication failed")
+	// ErrDecryptSerialization means the plaintext is not a valid serialized value.
+	ErrDecryptSerialization = errors.New("decrypt: invalid serialized value")
+)
+
+// Hash and cipher names understood by the registry.
+const (
+	HashSHA1   = "sha1"
+	HashSHA256 = "sha256"
+	HashSHA512 = "sha512"
+
+	CipherAES128CBC = "aes-128-cbc"
+	CipherAES256CBC = "aes-256-cbc"
+	CipherAES128GCM = "aes-128-gcm"
+)
+
+// CipherSpec describes a registered cipher.
+type CipherSpec struct {
+	// KeySize is the key length in bytes.
+	KeySize int
+	// AEAD is true for authenticated modes, which need a fresh nonce per value.
+	AEAD bool
+}
+
+var (
+	algorithmsMu sync.RWMutex
+	hashes       = map[string]func() hash.Hash{
+		HashSHA1:   sha1.New,
+		HashSHA256: sha256.New,
+		HashSHA512: sha512.New,
+	}
+	ciphers = map[string]CipherSpec{
+		CipherAES128CBC:        {KeySize: 16},
+		CipherAES256CBC:        {KeySize: 32},
+		CipherAES128GCM:        {KeySize: 16, AEAD: true},
+		CipherAES256GCM:        {KeySize: 32, AEAD: true},
+		CipherChaCha20Poly1305: {KeySize: 32, AEAD: true},
+	}
+)
+
+// RegisterHash makes a hash function available to Security and
+// SecurityAccessKey under name (case-insensitive).
+func RegisterHash(name string, fn func() hash.Hash) {
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+	hashes[strings.ToLower(name)] = fn
+}
+
+// LookupHash returns the registered hash function for name.
+func LookupHash(name string) (func() hash.Hash, error) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+	fn, ok := hashes[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash %q (supported: %s)", name, strings.Join(sortedKeys(hashes), ", "))
+	}
+	return fn, nil
+}
+
+// LookupCipher returns the spec of a registered cipher.
+func LookupCipher(name string) (CipherSpec, error) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+	spec, ok := ciphers[strings.ToLower(name)]
+	if !ok {
+		return CipherSpec{}, fmt.Errorf("unsupported cipher %q (supported: %s)", name, strings.Join(sortedKeys(ciphers), ", "))
+	}
+	return spec, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resolveHash returns the hash function for hashMethod, sha256 when unset.
+// Unknown names are an error.
+func resolveHash(hashMethod string) (func() hash.Hash, error) {
+	if hashMethod == "" {
+		hashMethod = HashSHA256
+	}
+	return LookupHash(hashMethod)
+}
+
+// resolveCipher returns the spec of cipherMode, aes-256-cbc when unset.
+// Unknown names are an error.
+func resolveCipher(cipherMode string) (CipherSpec, error) {
+	if cipherMode == "" {
+		cipherMode = CipherAES256CBC
+	}
+	return LookupCipher(cipherMode)
+}
+
+var (
+	_ Encrypter = (*Security)(nil)
+	_ Encrypter = (*SecurityAccessKey)(nil)
+	_ Encrypter = (*LaravelEncrypter)(nil)
+)
@@ -0,0 +1,254 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hashing drivers, named like Laravel's config/hashing.php.
+const (
+	HashDriverBcrypt   = "bcrypt"
+	HashDriverArgon2i  = "argon"
+	HashDriverArgon2id = "argon2id"
+)
+
+// ErrHashUnsupported means the hash was not made by a supported algorithm.
+var ErrHashUnsupported = errors.New("hashing: unsupported hash")
+
+// Hasher makes and checks password hashes like Laravel's Hash facade.
+type Hasher interface {
+	// Make hashes password.
+	Make(password string) (string, error)
+	// Check reports whether password matches hashed.
+	Check(password, hashed string) bool
+	// NeedsRehash reports whether hashed was made with another algorithm or
+	// other cost parameters than the hasher's.
+	NeedsRehash(hashed string) bool
+}
+
+// NewHasher returns the hasher for driver with the default costs of config/hashing.php.
+func NewHasher(driver string) (Hasher, error) {
+	switch strings.ToLower(driver) {
+	case HashDriverBcrypt:
+		return NewBcryptHasher(12), nil
+	case HashDriverArgon2i:
+		return NewArgon2Hasher(false, 65536, 4, 1), nil
+	case HashDriverArgon2id:
+		return NewArgon2Hasher(true, 65536, 4, 1), nil
+	}
+	return nil, fmt.Errorf("hashing: unsupported driver %q", driver)
+}
+
+// NewHasherFromEnv uses HASH_DRIVER (default bcrypt), BCRYPT_ROUNDS (12) and
+// ARGON_MEMORY (65536 KiB), ARGON_TIME (4) and ARGON_THREADS (1), like
+// Laravel's config/hashing.php.
+func NewHasherFromEnv() (Hasher, error) {
+	envInt := func(key string, fallback int) (int, error) {
+		value, err := strconv.Atoi(GetEnv(key, strconv.Itoa(fallback)))
+		if err != nil || value <= 0 {
+			return 0, fmt.Errorf("hashing: invalid %s", key)
+		}
+		return value, nil
+	}
+
+	driver := strings.ToLower(GetEnv("HASH_DRIVER", HashDriverBcrypt))
+	switch driver {
+	case HashDriverBcrypt:
+		rounds, err := envInt("BCRYPT_ROUNDS", 12)
+		if err != nil {
+			return nil, err
+		}
+		if rounds < bcrypt.MinCost || rounds > bcrypt.MaxCost {
+			return nil, fmt.Errorf("hashing: BCRYPT_ROUNDS must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return NewBcryptHasher(rounds), nil
+
+	case HashDriverArgon2i, HashDriverArgon2id:
+		memory, err := envInt("ARGON_MEMORY", 65536)
+		if err != nil {
+			return nil, err
+		}
+		time, err := envInt("ARGON_TIME", 4)
+		if err != nil {
+			return nil, err
+		}
+		threads, err := envInt("ARGON_THREADS", 1)
+		if err != nil || threads > 255 {
+			return nil, fmt.Errorf("hashing: invalid ARGON_THREADS")
+		}
+		return NewArgon2Hasher(driver == HashDriverArgon2id, uint32(memory), uint32(time), uint8(threads)), nil
+	}
+	return nil, fmt.Errorf("hashing: unsupported driver %q", driver)
+}
+
+// BcryptHasher produces PHP's "$2y$" bcrypt hashes.
+type BcryptHasher struct {
+	Rounds int
+}
+
+// NewBcryptHasher creates a BcryptHasher with the given cost.
+func NewBcryptHasher(rounds int) *BcryptHasher {
+	return &BcryptHasher{Rounds: rounds}
+}
+
+// Make implements Hasher. Passwords longer than 72 bytes are rejected rather
+// than silently truncated.
+func (h *BcryptHasher) Make(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Rounds)
+	if err != nil {
+		return "", err
+	}
+	// $2a$ and $2y$ are the same algorithm; PHP writes $2y$.
+	return "$2y$" + strings.TrimPrefix(string(hashed), "$2a$"), nil
+}
+
+// Check implements Hasher. Like Laravel's verify option, only bcrypt hashes are accepted.
+func (h *BcryptHasher) Check(password, hashed string) bool {
+	if !isBcryptHash(hashed) {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil
+}
+
+// NeedsRehash implements Hasher. Like password_needs_rehash, "$2a$" and "$2b$"
+// hashes are still checked but rehashed to PHP's "$2y$".
+func (h *BcryptHasher) NeedsRehash(hashed string) bool {
+	if !strings.HasPrefix(hashed, "$2y$") {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err != nil || cost != h.Rounds
+}
+
+func isBcryptHash(hashed string) bool {
+	for _, prefix := range []string{"$2y$", "$2a$", "$2b$"} {
+		if strings.HasPrefix(hashed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Argon2Hasher produces PHP's "$argon2i$" or "$argon2id$" hashes.
+type Argon2Hasher struct {
+	// ID selects argon2id instead of argon2i.
+	ID bool
+	// Memory is the memory cost in KiB.
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+// NewArgon2Hasher creates an Argon2Hasher with the given costs.
+func NewArgon2Hasher(id bool, memory, time uint32, threads uint8) *Argon2Hasher {
+	return &Argon2Hasher{ID: id, Memory: memory, Time: time, Threads: threads}
+}
+
+// argon2Hash is a decoded "$argon2id$v=19$m=65536,t=4,p=1$<salt>$<hash>".
+type argon2Hash struct {
+	id      bool
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func (h *Argon2Hasher) algorithm() string {
+	if h.ID {
+		return "argon2id"
+	}
+	return "argon2i"
+}
+
+// Make implements Hasher with PHP's 16 byte salt and 32 byte hash.
+func (h *Argon2Hasher) Make(password string) (string, error) {
+	if h.Memory == 0 || h.Time == 0 || h.Threads == 0 {
+		return "", errors.New("hashing: argon2 costs must be positive")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2Key(h.ID, password, salt, h.Time, h.Memory, h.Threads, 32)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", h.algorithm(), argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Check implements Hasher. Only hashes of the hasher's argon2 variant are accepted.
+func (h *Argon2Hasher) Check(password, hashed string) bool {
+	decoded, err := parseArgon2Hash(hashed)
+	if err != nil || decoded.id != h.ID {
+		return false
+	}
+	key := argon2Key(decoded.id, password, decoded.salt, decoded.time, decoded.memory, decoded.threads, uint32(len(decoded.key)))
+	return subtle.ConstantTimeCompare(key, decoded.key) == 1
+}
+
+// NeedsRehash implements Hasher.
+func (h *Argon2Hasher) NeedsRehash(hashed string) bool {
+	decoded, err := parseArgon2Hash(hashed)
+	if err != nil {
+		return true
+	}
+	return decoded.id != h.ID || decoded.memory != h.Memory || decoded.time != h.Time || decoded.threads != h.Threads
+}
+
+func argon2Key(id bool, password string, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if id {
+		return argon2.IDKey([]byte(password), salt, time, memory, threads, keyLen)
+	}
+	return argon2.Key([]byte(password), salt, time, memory, threads, keyLen)
+}
+
+func parseArgon2Hash(hashed string) (*argon2Hash, error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return nil, ErrHashUnsupported
+	}
+
+	decoded := &argon2Hash{}
+	switch parts[1] {
+	case "argon2i":
+	case "argon2id":
+		decoded.id = true
+	default:
+		return nil, ErrHashUnsupported
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrHashUnsupported
+	}
+	var threads uint32
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &decoded.memory, &decoded.time, &threads); err != nil {
+		return nil, ErrHashUnsupported
+	}
+	// Refuse costs no PHP configuration would produce, which could be used to exhaust memory.
+	if decoded.memory == 0 || decoded.memory > 4<<20 || decoded.time == 0 || decoded.time > 1<<10 || threads == 0 || threads > 255 {
+		return nil, ErrHashUnsupported
+	}
+	decoded.threads = uint8(threads)
+
+	var err error
+	if decoded.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(decoded.salt) < 8 {
+		return nil, ErrHashUnsupported
+	}
+	if decoded.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(decoded.key) < 4 {
+		return nil, ErrHashUnsupported
+	}
+	return decoded, nil
+}
+
+var (
+	_ Hasher = (*BcryptHasher)(nil)
+	_ Hasher = (*Argon2Hasher)(nil)
+)
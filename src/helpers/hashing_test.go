@@ -0,0 +1,98 @@
+package helpers
+
+import "testing"
+
+// Hashes made by PHP, or by libargon2, the library behind PHP's argon2 support.
+const (
+	// Laravel's UserFactory default password.
+	laravelBcryptPassword = "$2y$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi"
+	laravelBcryptSecret   = "$2y$10$.el7/OE7fuVCT0jIb6pIveUCQOuKiMJz9V8PmKOkk45YRnUlcOsM2"
+	// The password_hash example of the PHP manual.
+	phpArgon2i = "$argon2i$v=19$m=1024,t=2,p=2$YzJBSzV4TUhkMzc3d3laeg$zqU/1IN0/AogfP4cmSJI1vc8lpXRW9/S0sYY2i2jHT0"
+	// libargon2 test vectors for "password" salted with "somesalt".
+	libargon2i      = "$argon2i$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$RdescudvJCsgt3ub+b+dWRWJTmaaJObG"
+	libargon2id     = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+	libargon2idPar4 = "$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$GpZ3sK/oH9p7VIiV56G/64Zo/8GaUw434IimaPqxwCo"
+)
+
+func TestHasherCheckKnownHashes(t *testing.T) {
+	bcrypt := NewBcryptHasher(10)
+	argon2i := NewArgon2Hasher(false, 65536, 4, 1)
+	argon2id := NewArgon2Hasher(true, 65536, 4, 1)
+
+	tests := []struct {
+		name     string
+		hasher   Hasher
+		password string
+		hashed   string
+		want     bool
+	}{
+		{"bcrypt", bcrypt, "password", laravelBcryptPassword, true},
+		{"bcrypt wrong password", bcrypt, "Password", laravelBcryptPassword, false},
+		{"bcrypt secret", bcrypt, "secret", laravelBcryptSecret, true},
+		{"bcrypt rejects argon2", bcrypt, "password", libargon2id, false},
+		{"argon2i php", argon2i, "rasmuslerdorf", phpArgon2i, true},
+		{"argon2i php wrong password", argon2i, "rasmuslerdorF", phpArgon2i, false},
+		{"argon2i", argon2i, "password", libargon2i, true},
+		{"argon2i rejects argon2id", argon2i, "password", libargon2id, false},
+		{"argon2id", argon2id, "password", libargon2id, true},
+		{"argon2id parallel", argon2id, "password", libargon2idPar4, true},
+		{"argon2id wrong password", argon2id, "passwort", libargon2id, false},
+		{"argon2id rejects bcrypt", argon2id, "password", laravelBcryptPassword, false},
+		{"malformed", argon2id, "password", "$argon2id$v=19$m=65536$c29tZXNhbHQ$", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.Check(tt.password, tt.hashed); got != tt.want {
+				t.Fatalf("Check(%q, %q) = %v, want %v", tt.password, tt.hashed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasherNeedsRehash(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+		hashed string
+		want   bool
+	}{
+		{"bcrypt same rounds", NewBcryptHasher(10), laravelBcryptPassword, false},
+		{"bcrypt more rounds", NewBcryptHasher(12), laravelBcryptPassword, true},
+		{"bcrypt fewer rounds", NewBcryptHasher(4), laravelBcryptPassword, true},
+		{"bcrypt from argon2", NewBcryptHasher(10), libargon2id, true},
+		{"bcrypt 2a prefix", NewBcryptHasher(10), "$2a$" + laravelBcryptPassword[4:], true},
+		{"bcrypt 2b prefix", NewBcryptHasher(10), "$2b$" + laravelBcryptPassword[4:], true},
+		{"bcrypt malformed", NewBcryptHasher(10), "$2y$10$", true},
+		{"argon2i same costs", NewArgon2Hasher(false, 1024, 2, 2), phpArgon2i, false},
+		{"argon2i more memory", NewArgon2Hasher(false, 65536, 2, 2), phpArgon2i, true},
+		{"argon2i more time", NewArgon2Hasher(false, 1024, 4, 2), phpArgon2i, true},
+		{"argon2i fewer threads", NewArgon2Hasher(false, 1024, 2, 1), phpArgon2i, true},
+		{"argon2id from argon2i", NewArgon2Hasher(true, 65536, 2, 4), libargon2i, true},
+		{"argon2id same costs", NewArgon2Hasher(true, 65536, 2, 1), libargon2id, false},
+		{"argon2id from bcrypt", NewArgon2Hasher(true, 65536, 4, 1), laravelBcryptPassword, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.hashed); got != tt.want {
+				t.Fatalf("NeedsRehash(%q) = %v, want %v", tt.hashed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasherMakeRoundTrip(t *testing.T) {
+	for _, hasher := range []Hasher{NewBcryptHasher(4), NewArgon2Hasher(false, 1024, 1, 1), NewArgon2Hasher(true, 1024, 1, 1)} {
+		hashed, err := hasher.Make("password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !hasher.Check("password", hashed) || hasher.Check("other", hashed) || hasher.NeedsRehash(hashed) {
+			t.Fatalf("round trip of %q failed", hashed)
+		}
+	}
+	hashed, _ := NewBcryptHasher(4).Make("password")
+	if hashed[:4] != "$2y$" {
+		t.Fatalf("bcrypt hash %q does not use PHP's $2y$ prefix", hashed)
+	}
+}
@@ -0,0 +1,317 @@
+package helpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported JWT signing algorithms.
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgES256 = "ES256"
+)
+
+var (
+	ErrJWTMalformed     = errors.New("jwt: malformed token")
+	ErrJWTAlgorithm     = errors.New("jwt: algorithm not allowed")
+	ErrJWTUnknownKey    = errors.New("jwt: no key for token")
+	ErrJWTSignature     = errors.New("jwt: invalid signature")
+	ErrJWTExpired       = errors.New("jwt: token is expired")
+	ErrJWTNotYetValid   = errors.New("jwt: token is not valid yet")
+	ErrJWTIssuer        = errors.New("jwt: invalid issuer")
+	ErrJWTAudience      = errors.New("jwt: invalid audience")
+	ErrJWTKeyNotSupport = errors.New("jwt: unsupported key type")
+)
+
+// JWTClaims holds the decoded payload of a verified token.
+type JWTClaims map[string]interface{}
+
+// Subject returns the "sub" claim as a string.
+func (c JWTClaims) Subject() string {
+	return ClaimString(c["sub"])
+}
+
+// Audience returns the "aud" claim, which may be a string or a list.
+func (c JWTClaims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		result := make([]string, 0, len(aud))
+		for _, item := range aud {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// JWTKeySource resolves the verification key for a token header.
+// The returned key is []byte for HS256, *rsa.PublicKey for RS256 and
+// *ecdsa.PublicKey for ES256.
+type JWTKeySource interface {
+	Key(kid, alg string) (interface{}, error)
+}
+
+// JWTConfig configures claim validation.
+type JWTConfig struct {
+	// Algorithms allowed in the token header. Empty allows every supported algorithm.
+	Algorithms []string
+	// Issuer, when set, must equal the "iss" claim.
+	Issuer string
+	// Audience, when set, must intersect the "aud" claim.
+	Audience []string
+	// ClockSkew is tolerated when checking "exp" and "nbf".
+	ClockSkew time.Duration
+	// Now overrides the clock, mainly for tests.
+	Now func() time.Time
+}
+
+// JWTVerifier verifies compact JWS tokens locally.
+type JWTVerifier struct {
+	config JWTConfig
+	keys   JWTKeySource
+}
+
+// NewJWTVerifier creates a new instance of JWTVerifier.
+func NewJWTVerifier(keys JWTKeySource, config JWTConfig) *JWTVerifier {
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &JWTVerifier{config: config, keys: keys}
+}
+
+// NewJWTVerifierFromEnv builds a verifier from the variables used by Laravel jwt-auth:
+// JWT_ALGO, JWT_SECRET, JWT_PUBLIC_KEY (PEM file path), JWT_JWKS (file path or URL),
+// JWT_ISSUER, JWT_AUDIENCE (comma separated) and JWT_LEEWAY (seconds).
+func NewJWTVerifierFromEnv() (*JWTVerifier, error) {
+	config := JWTConfig{
+		Issuer: GetEnv("JWT_ISSUER", ""),
+	}
+	if algo := GetEnv("JWT_ALGO", ""); algo != "" {
+		config.Algorithms = []string{algo}
+	}
+	if audience := GetEnv("JWT_AUDIENCE", ""); audience != "" {
+		for _, aud := range strings.Split(audience, ",") {
+			if aud = strings.TrimSpace(aud); aud != "" {
+				config.Audience = append(config.Audience, aud)
+			}
+		}
+	}
+	if leeway := GetEnv("JWT_LEEWAY", ""); leeway != "" {
+		seconds, err := strconv.Atoi(leeway)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_LEEWAY: %v", err)
+		}
+		config.ClockSkew = time.Duration(seconds) * time.Second
+	}
+
+	var source JWTKeySource
+	switch {
+	case GetEnv("JWT_JWKS", "") != "":
+		source = NewJWKSKeySource(GetEnv("JWT_JWKS", ""), 0)
+	case GetEnv("JWT_PUBLIC_KEY", "") != "":
+		key, err := LoadPEMKey(GetEnv("JWT_PUBLIC_KEY", ""))
+		if err != nil {
+			return nil, err
+		}
+		source = StaticKeys{"": key}
+	case GetEnv("JWT_SECRET", "") != "":
+		source = StaticKeys{"": []byte(GetEnv("JWT_SECRET", ""))}
+	default:
+		return nil, errors.New("no JWT key configured (JWT_JWKS, JWT_PUBLIC_KEY or JWT_SECRET)")
+	}
+
+	return NewJWTVerifier(source, config), nil
+}
+
+// Verify checks the signature and the registered claims of token.
+func (v *JWTVerifier) Verify(token string) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if !v.algorithmAllowed(header.Alg) {
+		return nil, fmt.Errorf("%w: %q", ErrJWTAlgorithm, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+
+	key, err := v.keys.Key(header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *JWTVerifier) algorithmAllowed(alg string) bool {
+	switch alg {
+	case JWTAlgHS256, JWTAlgRS256, JWTAlgES256:
+	default:
+		return false
+	}
+	if len(v.config.Algorithms) == 0 {
+		return true
+	}
+	for _, allowed := range v.config.Algorithms {
+		if allowed == alg {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *JWTVerifier) validateClaims(claims JWTClaims) error {
+	now := v.config.Now()
+	skew := v.config.ClockSkew
+
+	if exp, ok, err := claimTime(claims, "exp"); err != nil {
+		return err
+	} else if ok && !now.Add(-skew).Before(exp) {
+		return ErrJWTExpired
+	}
+	if nbf, ok, err := claimTime(claims, "nbf"); err != nil {
+		return err
+	} else if ok && now.Add(skew).Before(nbf) {
+		return ErrJWTNotYetValid
+	}
+
+	if v.config.Issuer != "" && ClaimString(claims["iss"]) != v.config.Issuer {
+		return ErrJWTIssuer
+	}
+
+	if len(v.config.Audience) > 0 {
+		matched := false
+		for _, aud := range claims.Audience() {
+			for _, expected := range v.config.Audience {
+				if aud == expected {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return ErrJWTAudience
+		}
+	}
+	return nil
+}
+
+func verifyJWTSignature(alg string, key interface{}, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch alg {
+	case JWTAlgHS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrJWTKeyNotSupport
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrJWTSignature
+		}
+	case JWTAlgRS256:
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrJWTKeyNotSupport
+		}
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrJWTSignature
+		}
+	case JWTAlgES256:
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrJWTKeyNotSupport
+		}
+		// JWS encodes ES256 signatures as the fixed size concatenation r || s.
+		if len(signature) != 64 {
+			return ErrJWTSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return ErrJWTSignature
+		}
+	default:
+		return ErrJWTAlgorithm
+	}
+	return nil
+}
+
+func decodeJWTSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+func claimTime(claims JWTClaims, name string) (time.Time, bool, error) {
+	value, exists := claims[name]
+	if !exists || value == nil {
+		return time.Time{}, false, nil
+	}
+	switch v := value.(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true, nil
+	case string:
+		seconds, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			return time.Unix(seconds, 0), true, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%w: invalid %q claim", ErrJWTMalformed, name)
+}
+
+// ClaimString formats a decoded JSON value, such as a claim or a user id, as a
+// string. Numbers are written in full: 1234567 is "1234567", not "1.234567e+06".
+func ClaimString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
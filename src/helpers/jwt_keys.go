@@ -0,0 +1,336 @@
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultJWKSRefresh is how long a fetched JWKS document is cached.
+const DefaultJWKSRefresh = 15 * time.Minute
+
+// jwksMinRefresh limits how often the JWKS document is fetched.
+const jwksMinRefresh = 30 * time.Second
+
+// StaticKeys is a JWTKeySource backed by a fixed map of key ID to key.
+// The "" entry is used when the token has no "kid" or the kid is unknown.
+type StaticKeys map[string]interface{}
+
+// Key implements JWTKeySource.
+func (s StaticKeys) Key(kid, alg string) (interface{}, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	if key, ok := s[""]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrJWTUnknownKey, kid)
+}
+
+// LoadPEMKey reads a public key, certificate or private key from a PEM file and
+// returns the public part usable by JWTVerifier.
+func LoadPEMKey(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file: %v", err)
+	}
+	return ParsePEMKey(data)
+}
+
+// ParsePEMKey parses the first PEM block of data into a public key.
+func ParsePEMKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			return &key.PublicKey, nil
+		case *ecdsa.PrivateKey:
+			return &key.PublicKey, nil
+		}
+		return nil, ErrJWTKeyNotSupport
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// JWKSKeySource loads keys from a JWKS document on disk or over HTTP(S).
+// The document is cached for the refresh interval and refetched early when a
+// token references an unknown key ID. Fetches run at most every jwksMinRefresh;
+// while one runs, or after one failed, tokens are verified with the cached keys.
+type JWKSKeySource struct {
+	Location string
+	Refresh  time.Duration
+	Client   *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]jwksEntry
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	lastErr     error
+	// loading is closed when the running fetch finishes.
+	loading chan struct{}
+}
+
+type jwksEntry struct {
+	alg string
+	key interface{}
+}
+
+// NewJWKSKeySource creates a JWKS backed key source. A zero refresh uses DefaultJWKSRefresh.
+func NewJWKSKeySource(location string, refresh time.Duration) *JWKSKeySource {
+	if refresh <= 0 {
+		refresh = DefaultJWKSRefresh
+	}
+	return &JWKSKeySource{
+		Location: location,
+		Refresh:  refresh,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Key implements JWTKeySource.
+func (j *JWKSKeySource) Key(kid, alg string) (interface{}, error) {
+	keys, err := j.current(false)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := lookupJWKS(keys, kid, alg); ok {
+		return key, nil
+	}
+
+	// Unknown kid: the issuer may have rotated keys.
+	keys, err = j.current(true)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := lookupJWKS(keys, kid, alg); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrJWTUnknownKey, kid)
+}
+
+// current returns the cached keys, first refetching them when they are stale
+// or force is set. The fetch runs without holding j.mu; only callers that have
+// no keys yet wait for it.
+func (j *JWKSKeySource) current(force bool) (map[string]jwksEntry, error) {
+	j.mu.Lock()
+	now := time.Now()
+	stale := force || j.keys == nil || now.Sub(j.fetchedAt) > j.Refresh
+	switch {
+	case stale && j.loading == nil && now.Sub(j.lastAttempt) >= jwksMinRefresh:
+		loading := make(chan struct{})
+		j.loading = loading
+		j.lastAttempt = now
+		j.mu.Unlock()
+
+		keys, err := j.fetch()
+
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+			j.fetchedAt = now
+		}
+		j.lastErr = err
+		j.loading = nil
+		close(loading)
+	case j.keys == nil && j.loading != nil:
+		loading := j.loading
+		j.mu.Unlock()
+		<-loading
+		j.mu.Lock()
+	}
+	keys, err := j.keys, j.lastErr
+	j.mu.Unlock()
+
+	if keys == nil {
+		if err == nil {
+			err = errors.New("error loading JWKS: no keys loaded")
+		}
+		return nil, err
+	}
+	return keys, nil
+}
+
+func lookupJWKS(keys map[string]jwksEntry, kid, alg string) (interface{}, bool) {
+	if kid != "" {
+		entry, ok := keys[kid]
+		if ok && (entry.alg == "" || entry.alg == alg) {
+			return entry.key, true
+		}
+		return nil, false
+	}
+
+	// Without a kid the match must be unambiguous.
+	var found interface{}
+	count := 0
+	for _, entry := range keys {
+		if entry.alg == alg || (entry.alg == "" && jwkMatchesAlg(entry.key, alg)) {
+			found = entry.key
+			count++
+		}
+	}
+	return found, count == 1
+}
+
+func (j *JWKSKeySource) fetch() (map[string]jwksEntry, error) {
+	data, err := j.read()
+	if err != nil {
+		return nil, fmt.Errorf("error loading JWKS: %v", err)
+	}
+	return parseJWKS(data)
+}
+
+func (j *JWKSKeySource) read() ([]byte, error) {
+	if !strings.HasPrefix(j.Location, "http://") && !strings.HasPrefix(j.Location, "https://") {
+		return os.ReadFile(strings.TrimPrefix(j.Location, "file://"))
+	}
+
+	client := j.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(j.Location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseJWKS decodes a JWKS document into key ID to key entries.
+// Keys that are not usable for signature verification are skipped.
+func parseJWKS(data []byte) (map[string]jwksEntry, error) {
+	var document struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid JWKS document: %v", err)
+	}
+
+	keys := make(map[string]jwksEntry, len(document.Keys))
+	for i, jwk := range document.Keys {
+		if use, _ := jwk["use"].(string); use != "" && use != "sig" {
+			continue
+		}
+		key, err := parseJWK(jwk)
+		if err != nil {
+			continue
+		}
+		kid, _ := jwk["kid"].(string)
+		if kid == "" {
+			kid = fmt.Sprintf("#%d", i)
+		}
+		alg, _ := jwk["alg"].(string)
+		keys[kid] = jwksEntry{alg: alg, key: key}
+	}
+	return keys, nil
+}
+
+func parseJWK(jwk map[string]interface{}) (interface{}, error) {
+	field := func(name string) ([]byte, error) {
+		value, _ := jwk[name].(string)
+		if value == "" {
+			return nil, fmt.Errorf("missing %q", name)
+		}
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	}
+
+	switch jwk["kty"] {
+	case "RSA":
+		n, err := field("n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := field("e")
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if jwk["crv"] != "P-256" {
+			return nil, ErrJWTKeyNotSupport
+		}
+		x, err := field("x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := field("y")
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if _, err := key.ECDH(); err != nil {
+			return nil, fmt.Errorf("invalid EC key: %v", err)
+		}
+		return key, nil
+	case "oct":
+		return field("k")
+	}
+	return nil, ErrJWTKeyNotSupport
+}
+
+func jwkMatchesAlg(key interface{}, alg string) bool {
+	switch key.(type) {
+	case []byte:
+		return alg == JWTAlgHS256
+	case *rsa.PublicKey:
+		return alg == JWTAlgRS256
+	case *ecdsa.PublicKey:
+		return alg == JWTAlgES256
+	}
+	return false
+}
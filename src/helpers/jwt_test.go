@@ -0,0 +1,245 @@
+package helpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var jwtTestNow = time.Unix(1700000000, 0)
+
+// signTestJWT builds a compact token; key is []byte, *rsa.PrivateKey or
+// *ecdsa.PrivateKey, or nil for an unsigned token.
+func signTestJWT(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+	segment := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := segment(header) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestJWTVerifierAlgorithms(t *testing.T) {
+	secret := []byte("jwt-test-secret")
+	rsaKey := newTestRSAKey(t)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{"sub": 1234567}
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})
+
+	tests := []struct {
+		name    string
+		keys    JWTKeySource
+		config  JWTConfig
+		token   string
+		wantErr error
+	}{
+		{"hs256", StaticKeys{"": secret}, JWTConfig{}, signTestJWT(t, map[string]interface{}{"alg": "HS256"}, claims, secret), nil},
+		{"rs256", StaticKeys{"": &rsaKey.PublicKey}, JWTConfig{}, signTestJWT(t, map[string]interface{}{"alg": "RS256"}, claims, rsaKey), nil},
+		{"es256", StaticKeys{"": &ecKey.PublicKey}, JWTConfig{}, signTestJWT(t, map[string]interface{}{"alg": "ES256"}, claims, ecKey), nil},
+		{"wrong secret", StaticKeys{"": secret}, JWTConfig{}, signTestJWT(t, map[string]interface{}{"alg": "HS256"}, claims, []byte("other")), ErrJWTSignature},
+		// An HS256 token MACed with the public key must not verify against the RSA key.
+		{"hs256 against rsa key", StaticKeys{"": &rsaKey.PublicKey}, JWTConfig{}, signTestJWT(t, map[string]interface{}{"alg": "HS256"}, claims, rsaPEM), ErrJWTKeyNotSupport},
+		{"rs256 against secret", StaticKeys{"": secret}, JWTConfig{}, signTestJWT(t, map[string]interface{}{"alg": "RS256"}, claims, rsaKey), ErrJWTKeyNotSupport},
+		{"alg not allowed", StaticKeys{"": secret}, JWTConfig{Algorithms: []string{JWTAlgRS256}}, signTestJWT(t, map[string]interface{}{"alg": "HS256"}, claims, secret), ErrJWTAlgorithm},
+		{"alg none", StaticKeys{"": secret}, JWTConfig{}, signTestJWT(t, map[string]interface{}{"alg": "none"}, claims, nil), ErrJWTAlgorithm},
+		{"alg none lowercase", StaticKeys{"": secret}, JWTConfig{}, signTestJWT(t, map[string]interface{}{"alg": "None"}, claims, nil), ErrJWTAlgorithm},
+		{"malformed", StaticKeys{"": secret}, JWTConfig{}, "a.b", ErrJWTMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Now = func() time.Time { return jwtTestNow }
+			got, err := NewJWTVerifier(tt.keys, tt.config).Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Subject() != "1234567" {
+				t.Fatalf("Subject() = %q, want 1234567", got.Subject())
+			}
+		})
+	}
+}
+
+func TestJWTVerifierClaims(t *testing.T) {
+	secret := []byte("jwt-test-secret")
+	now := jwtTestNow.Unix()
+
+	tests := []struct {
+		name    string
+		config  JWTConfig
+		claims  map[string]interface{}
+		wantErr error
+	}{
+		{"valid", JWTConfig{}, map[string]interface{}{"exp": now + 60, "nbf": now - 60}, nil},
+		{"expired", JWTConfig{}, map[string]interface{}{"exp": now - 1}, ErrJWTExpired},
+		{"expires now", JWTConfig{}, map[string]interface{}{"exp": now}, ErrJWTExpired},
+		{"expired within skew", JWTConfig{ClockSkew: 30 * time.Second}, map[string]interface{}{"exp": now - 10}, nil},
+		{"expired beyond skew", JWTConfig{ClockSkew: 30 * time.Second}, map[string]interface{}{"exp": now - 31}, ErrJWTExpired},
+		{"not yet valid", JWTConfig{}, map[string]interface{}{"nbf": now + 10}, ErrJWTNotYetValid},
+		{"nbf within skew", JWTConfig{ClockSkew: 30 * time.Second}, map[string]interface{}{"nbf": now + 10}, nil},
+		{"string exp", JWTConfig{}, map[string]interface{}{"exp": fmt.Sprint(now + 60)}, nil},
+		{"invalid exp", JWTConfig{}, map[string]interface{}{"exp": "tomorrow"}, ErrJWTMalformed},
+		{"issuer", JWTConfig{Issuer: "auth"}, map[string]interface{}{"iss": "auth"}, nil},
+		{"wrong issuer", JWTConfig{Issuer: "auth"}, map[string]interface{}{"iss": "other"}, ErrJWTIssuer},
+		{"aud string", JWTConfig{Audience: []string{"grades"}}, map[string]interface{}{"aud": "grades"}, nil},
+		{"aud list", JWTConfig{Audience: []string{"grades"}}, map[string]interface{}{"aud": []string{"auth", "grades"}}, nil},
+		{"aud string mismatch", JWTConfig{Audience: []string{"grades"}}, map[string]interface{}{"aud": "auth"}, ErrJWTAudience},
+		{"aud list mismatch", JWTConfig{Audience: []string{"grades"}}, map[string]interface{}{"aud": []string{"auth"}}, ErrJWTAudience},
+		{"aud missing", JWTConfig{Audience: []string{"grades"}}, map[string]interface{}{}, ErrJWTAudience},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Now = func() time.Time { return jwtTestNow }
+			token := signTestJWT(t, map[string]interface{}{"alg": "HS256"}, tt.claims, secret)
+			if _, err := NewJWTVerifier(StaticKeys{"": secret}, tt.config).Verify(token); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func testJWKS(t *testing.T, keys map[string]*rsa.PublicKey) []byte {
+	t.Helper()
+	var document struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		document.Keys = append(document.Keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestJWKSKeySourceRefresh(t *testing.T) {
+	oldKey, newKey := newTestRSAKey(t), newTestRSAKey(t)
+	var document atomic.Value
+	document.Store(testJWKS(t, map[string]*rsa.PublicKey{"old": &oldKey.PublicKey}))
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(document.Load().([]byte))
+	}))
+	defer server.Close()
+
+	source := NewJWKSKeySource(server.URL, time.Hour)
+	verifier := NewJWTVerifier(source, JWTConfig{})
+	oldToken := signTestJWT(t, map[string]interface{}{"alg": "RS256", "kid": "old"}, map[string]interface{}{}, oldKey)
+	newToken := signTestJWT(t, map[string]interface{}{"alg": "RS256", "kid": "new"}, map[string]interface{}{}, newKey)
+
+	if _, err := verifier.Verify(oldToken); err != nil {
+		t.Fatalf("Verify(old) error = %v", err)
+	}
+	// The issuer rotates; unknown kids may not refetch more than once per jwksMinRefresh.
+	document.Store(testJWKS(t, map[string]*rsa.PublicKey{"old": &oldKey.PublicKey, "new": &newKey.PublicKey}))
+	for i := 0; i < 10; i++ {
+		if _, err := verifier.Verify(newToken); !errors.Is(err, ErrJWTUnknownKey) {
+			t.Fatalf("Verify(new) error = %v, want %v", err, ErrJWTUnknownKey)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("fetched %d times within jwksMinRefresh, want 1", got)
+	}
+
+	source.mu.Lock()
+	source.lastAttempt = source.lastAttempt.Add(-jwksMinRefresh)
+	source.mu.Unlock()
+	if _, err := verifier.Verify(newToken); err != nil {
+		t.Fatalf("Verify(new) after jwksMinRefresh error = %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("fetched %d times, want 2", got)
+	}
+}
+
+func TestJWKSKeySourceKeepsKeysOnFailure(t *testing.T) {
+	key := newTestRSAKey(t)
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write(testJWKS(t, map[string]*rsa.PublicKey{"k1": &key.PublicKey}))
+	}))
+	defer server.Close()
+
+	source := NewJWKSKeySource(server.URL, time.Hour)
+	if _, err := source.Key("k1", JWTAlgRS256); err != nil {
+		t.Fatal(err)
+	}
+	fail.Store(true)
+	source.mu.Lock()
+	source.fetchedAt = source.fetchedAt.Add(-2 * time.Hour)
+	source.lastAttempt = source.lastAttempt.Add(-jwksMinRefresh)
+	source.mu.Unlock()
+	if _, err := source.Key("k1", JWTAlgRS256); err != nil {
+		t.Fatalf("Key() with a failing refresh error = %v, want the cached key", err)
+	}
+	// Without a kid the key must match the algorithm.
+	if _, err := source.Key("", JWTAlgHS256); !errors.Is(err, ErrJWTUnknownKey) {
+		t.Fatalf("Key(\"\", HS256) error = %v, want %v", err, ErrJWTUnknownKey)
+	}
+}
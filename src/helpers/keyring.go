@@ -0,0 +1,157 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyIDHeader hints which key encrypted the Access-Key, so the receiver tries it first.
+const KeyIDHeader = "Access-Key-Id"
+
+// AccessKey is one APP_KEY of a Keyring.
+type AccessKey struct {
+	ID     string
+	Secret string
+	// RetireAt is when a previous key stops being accepted; zero means never.
+	RetireAt time.Time
+}
+
+// KeyUsage reports how often callers used a key.
+type KeyUsage struct {
+	ID       string     `json:"id"`
+	Active   bool       `json:"active"`
+	RetireAt *time.Time `json:"retire_at,omitempty"`
+	Uses     uint64     `json:"uses"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// Keyring holds the active APP_KEY, used to issue Access-Keys, and previous keys
+// still accepted until their retirement time. It lets services rotate APP_KEY
+// one at a time instead of all at once.
+type Keyring struct {
+	active   AccessKey
+	previous []AccessKey
+	now      func() time.Time
+
+	mu       sync.Mutex
+	uses     map[string]uint64
+	lastUsed map[string]time.Time
+}
+
+// NewKeyring creates a Keyring. Keys without an ID get KeyID(secret).
+func NewKeyring(active AccessKey, previous ...AccessKey) *Keyring {
+	if active.ID == "" {
+		active.ID = KeyID(active.Secret)
+	}
+	for i := range previous {
+		if previous[i].ID == "" {
+			previous[i].ID = KeyID(previous[i].Secret)
+		}
+	}
+	return &Keyring{
+		active:   active,
+		previous: previous,
+		now:      time.Now,
+		uses:     map[string]uint64{},
+		lastUsed: map[string]time.Time{},
+	}
+}
+
+// NewKeyringFromEnv builds a Keyring from APP_KEY and APP_PREVIOUS_KEYS, the
+// comma separated list Laravel uses for rotation. Retirement times live in
+// APP_PREVIOUS_KEYS_RETIRE_AT, a comma separated list of RFC 3339 times matched
+// to the previous keys by position; an empty entry never retires its key. This
+// keeps APP_PREVIOUS_KEYS readable by Laravel apps sharing the .env.
+func NewKeyringFromEnv() (*Keyring, error) {
+	active := AccessKey{Secret: NewSecurityAccessKey().GetKey()}
+
+	secrets := previousKeysFromEnv()
+	var retireAt []string
+	if value := strings.TrimSpace(GetEnv("APP_PREVIOUS_KEYS_RETIRE_AT", "")); value != "" {
+		retireAt = strings.Split(value, ",")
+	}
+	if len(retireAt) > len(secrets) {
+		return nil, fmt.Errorf("APP_PREVIOUS_KEYS_RETIRE_AT has %d entries for %d previous keys", len(retireAt), len(secrets))
+	}
+
+	previous := make([]AccessKey, len(secrets))
+	for i, secret := range secrets {
+		previous[i].Secret = secret
+		if i >= len(retireAt) || strings.TrimSpace(retireAt[i]) == "" {
+			continue
+		}
+		retire, err := time.Parse(time.RFC3339, strings.TrimSpace(retireAt[i]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid retirement time for previous key %d: %v", i+1, err)
+		}
+		previous[i].RetireAt = retire
+	}
+	return NewKeyring(active, previous...), nil
+}
+
+// KeyID derives a public identifier from a secret: the first 8 bytes of its SHA-256, in hex.
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Active returns the key used to issue Access-Keys.
+func (k *Keyring) Active() AccessKey {
+	return k.active
+}
+
+// Candidates returns the keys currently accepted, the key matching hint first
+// and then the active key followed by previous keys in order.
+func (k *Keyring) Candidates(hint string) []AccessKey {
+	now := k.now()
+	keys := make([]AccessKey, 0, 1+len(k.previous))
+	keys = append(keys, k.active)
+	for _, key := range k.previous {
+		if key.RetireAt.IsZero() || now.Before(key.RetireAt) {
+			keys = append(keys, key)
+		}
+	}
+
+	if hint != "" {
+		for i, key := range keys {
+			if key.ID == hint {
+				keys[0], keys[i] = keys[i], keys[0]
+				break
+			}
+		}
+	}
+	return keys
+}
+
+// Record counts one accepted Access-Key encrypted with the key id.
+func (k *Keyring) Record(id string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.uses[id]++
+	k.lastUsed[id] = k.now()
+}
+
+// Usage reports, for every configured key, how often callers still use it.
+func (k *Keyring) Usage() []KeyUsage {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keys := append([]AccessKey{k.active}, k.previous...)
+	usage := make([]KeyUsage, 0, len(keys))
+	for i, key := range keys {
+		entry := KeyUsage{ID: key.ID, Active: i == 0, Uses: k.uses[key.ID]}
+		if !key.RetireAt.IsZero() {
+			retireAt := key.RetireAt
+			entry.RetireAt = &retireAt
+		}
+		if lastUsed, ok := k.lastUsed[key.ID]; ok {
+			entry.LastUsed = &lastUsed
+		}
+		usage = append(usage, entry)
+	}
+	return usage
+}
@@ -0,0 +1,129 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func keyIDs(keys []AccessKey) []string {
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = key.ID
+	}
+	return ids
+}
+
+func TestKeyringCandidates(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ring := NewKeyring(
+		AccessKey{ID: "active", Secret: "a"},
+		AccessKey{ID: "old", Secret: "o"},
+		AccessKey{ID: "retiring", Secret: "r", RetireAt: now.Add(time.Hour)},
+	)
+	ring.now = func() time.Time { return now }
+
+	tests := []struct {
+		name string
+		hint string
+		want []string
+	}{
+		{"no hint", "", []string{"active", "old", "retiring"}},
+		{"hint first", "retiring", []string{"retiring", "old", "active"}},
+		{"unknown hint", "missing", []string{"active", "old", "retiring"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyIDs(ring.Candidates(tt.hint)); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Candidates(%q) = %v, want %v", tt.hint, got, tt.want)
+			}
+		})
+	}
+
+	// At RetireAt the key is no longer accepted, even when hinted.
+	now = now.Add(time.Hour)
+	if got := keyIDs(ring.Candidates("retiring")); !reflect.DeepEqual(got, []string{"active", "old"}) {
+		t.Fatalf("Candidates() after retirement = %v", got)
+	}
+}
+
+func TestKeyringDefaultIDs(t *testing.T) {
+	ring := NewKeyring(AccessKey{Secret: "a"}, AccessKey{Secret: "o"})
+	if got := keyIDs(ring.Candidates("")); !reflect.DeepEqual(got, []string{KeyID("a"), KeyID("o")}) {
+		t.Fatalf("ids = %v", got)
+	}
+	if id := KeyID("a"); len(id) != 16 || id == KeyID("o") {
+		t.Fatalf("KeyID() = %q", id)
+	}
+}
+
+func TestNewKeyringFromEnv(t *testing.T) {
+	const (
+		activeKey = "base64:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+		oldKey    = "base64:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+		olderKey  = "base64:b2xkZXIta2V5LTAxMjM0NTY3ODlhYmNkZWYwMTIzNDU="
+	)
+	t.Setenv("APP_KEY", activeKey)
+	t.Setenv("APP_PREVIOUS_KEYS", oldKey+", "+olderKey)
+
+	t.Run("retire at by position", func(t *testing.T) {
+		t.Setenv("APP_PREVIOUS_KEYS_RETIRE_AT", " ,2023-11-14T22:13:20Z")
+		ring, err := NewKeyringFromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ring.Active().Secret != activeKey {
+			t.Fatalf("active = %q", ring.Active().Secret)
+		}
+		usage := ring.Usage()
+		if len(usage) != 3 || usage[1].RetireAt != nil || usage[2].RetireAt == nil || !usage[2].RetireAt.Equal(time.Unix(1700000000, 0)) {
+			t.Fatalf("usage = %+v", usage)
+		}
+
+		ring.now = func() time.Time { return time.Unix(1700000000, 0) }
+		if got := keyIDs(ring.Candidates("")); !reflect.DeepEqual(got, []string{KeyID(activeKey), KeyID(oldKey)}) {
+			t.Fatalf("Candidates() = %v", got)
+		}
+	})
+
+	t.Run("unset never retires", func(t *testing.T) {
+		t.Setenv("APP_PREVIOUS_KEYS_RETIRE_AT", "")
+		ring, err := NewKeyringFromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(ring.Candidates("")); got != 3 {
+			t.Fatalf("%d candidates, want 3", got)
+		}
+	})
+
+	t.Run("too many entries", func(t *testing.T) {
+		t.Setenv("APP_PREVIOUS_KEYS_RETIRE_AT", ",,2023-11-14T22:13:20Z")
+		if _, err := NewKeyringFromEnv(); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("invalid time", func(t *testing.T) {
+		t.Setenv("APP_PREVIOUS_KEYS_RETIRE_AT", "2023-11-14")
+		if _, err := NewKeyringFromEnv(); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestKeyringUsage(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ring := NewKeyring(AccessKey{ID: "active", Secret: "a"}, AccessKey{ID: "old", Secret: "o"})
+	ring.now = func() time.Time { return now }
+
+	ring.Record("old")
+	ring.Record("old")
+	usage := ring.Usage()
+	if !usage[0].Active || usage[0].Uses != 0 || usage[0].LastUsed != nil {
+		t.Fatalf("active usage = %+v", usage[0])
+	}
+	if usage[1].Active || usage[1].Uses != 2 || usage[1].LastUsed == nil || !usage[1].LastUsed.Equal(now) {
+		t.Fatalf("old usage = %+v", usage[1])
+	}
+}
@@ -0,0 +1,106 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Names of the cookies and headers Laravel uses for CSRF protection.
+const (
+	XSRFCookieName = "XSRF-TOKEN"
+	XSRFHeader     = "X-XSRF-TOKEN"
+	CSRFHeader     = "X-CSRF-TOKEN"
+	CSRFInput      = "_token"
+)
+
+// cookiePrefixLength is the length of hash_hmac('sha1', ...) plus the "|" separator.
+const cookiePrefixLength = 41
+
+// ErrCookieInvalid means a cookie could not be decrypted or was encrypted for another cookie name.
+var ErrCookieInvalid = errors.New("laravel cookie: invalid value")
+
+// LaravelCookies encrypts and decrypts cookies like Laravel's EncryptCookies
+// middleware: the value is prefixed with CookieValuePrefix::create() and
+// encrypted, unserialized, with the application's encrypter.
+type LaravelCookies struct {
+	Encrypter *LaravelEncrypter
+}
+
+// NewLaravelCookies creates a LaravelCookies for encrypter.
+func NewLaravelCookies(encrypter *LaravelEncrypter) *LaravelCookies {
+	return &LaravelCookies{Encrypter: encrypter}
+}
+
+// NewLaravelCookiesFromEnv uses NewLaravelEncrypterFromEnv.
+func NewLaravelCookiesFromEnv() (*LaravelCookies, error) {
+	encrypter, err := NewLaravelEncrypterFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewLaravelCookies(encrypter), nil
+}
+
+// LaravelSessionCookieName is SESSION_COOKIE, or slug(APP_NAME)."_session" as in config/session.php.
+func LaravelSessionCookieName() string {
+	if name := GetEnv("SESSION_COOKIE", ""); name != "" {
+		return name
+	}
+	return laravelSlug(GetEnv("APP_NAME", "laravel")) + "_session"
+}
+
+// cookiePrefix is CookieValuePrefix::create($name, $key): hash_hmac('sha1', $name.'v2', $key).'|'.
+func cookiePrefix(name string, key []byte) string {
+	mac := hmac.New(sha1.New, key)
+	mac.Write([]byte(name + "v2"))
+	return hex.EncodeToString(mac.Sum(nil)) + "|"
+}
+
+// EncodeCookieValue URL encodes a cookie value like Symfony's Cookie
+// (rawurlencode), so PHP's $_COOKIE decodes it back unchanged.
+func EncodeCookieValue(value string) string {
+	return rawURLEncode(value)
+}
+
+// DecodeCookieValue undoes the URL encoding PHP applies to cookie values.
+func DecodeCookieValue(value string) (string, error) {
+	return url.PathUnescape(value)
+}
+
+// Encrypt encrypts value for the cookie called name.
+func (l *LaravelCookies) Encrypt(name, value string) (string, error) {
+	return l.Encrypter.EncryptString(cookiePrefix(name, l.Encrypter.key) + value)
+}
+
+// Decrypt decrypts the cookie called name. Values encrypted for another cookie
+// name, e.g. copied from one cookie to another, are rejected like in Laravel.
+func (l *LaravelCookies) Decrypt(name, value string) (string, error) {
+	decrypted, err := l.Encrypter.DecryptString(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCookieInvalid, err)
+	}
+	for _, key := range append([][]byte{l.Encrypter.key}, l.Encrypter.previousKeys...) {
+		if prefix := cookiePrefix(name, key); hmac.Equal([]byte(decrypted[:min(len(prefix), len(decrypted))]), []byte(prefix)) {
+			return decrypted[len(prefix):], nil
+		}
+	}
+	return "", ErrCookieInvalid
+}
+
+// DecryptXSRFHeader reads the X-XSRF-TOKEN header, which JavaScript clients
+// copy verbatim from the XSRF-TOKEN cookie. Like VerifyCsrfToken the prefix is
+// stripped without being checked.
+func (l *LaravelCookies) DecryptXSRFHeader(value string) (string, error) {
+	decrypted, err := l.Encrypter.DecryptString(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrCookieInvalid, err)
+	}
+	if len(decrypted) < cookiePrefixLength || !strings.HasSuffix(decrypted[:cookiePrefixLength], "|") {
+		return "", ErrCookieInvalid
+	}
+	return decrypted[cookiePrefixLength:], nil
+}
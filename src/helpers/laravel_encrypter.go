@@ -0,0 +1,292 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Ciphers supported by Laravel's Illuminate\Encryption\Encrypter.
+const (
+	LaravelAES128CBC = "aes-128-cbc"
+	LaravelAES256CBC = "aes-256-cbc"
+	LaravelAES128GCM = "aes-128-gcm"
+	LaravelAES256GCM = "aes-256-gcm"
+)
+
+var laravelCiphers = map[string]struct {
+	keySize int
+	aead    bool
+}{
+	LaravelAES128CBC: {16, false},
+	LaravelAES256CBC: {32, false},
+	LaravelAES128GCM: {16, true},
+	LaravelAES256GCM: {32, true},
+}
+
+var (
+	// ErrLaravelPayloadInvalid means the payload is not a Laravel encrypted payload.
+	ErrLaravelPayloadInvalid = errors.New("laravel encrypter: the payload is invalid")
+	// ErrLaravelMACInvalid means no configured key produced the payload MAC.
+	ErrLaravelMACInvalid = errors.New("laravel encrypter: the MAC is invalid")
+	// ErrLaravelDecrypt means the value could not be decrypted.
+	ErrLaravelDecrypt = errors.New("laravel encrypter: could not decrypt the data")
+)
+
+// laravelPayload is the JSON document inside Crypt::encrypt output.
+type laravelPayload struct {
+	IV    string `json:"iv"`
+	Value string `json:"value"`
+	MAC   string `json:"mac"`
+	Tag   string `json:"tag"`
+}
+
+// LaravelEncrypter reads and writes the payloads of Laravel's Crypt facade
+// (base64 JSON with iv, value, mac and tag) so values can travel between PHP and Go.
+type LaravelEncrypter struct {
+	key          []byte
+	previousKeys [][]byte
+	cipher       string
+}
+
+// NewLaravelEncrypter creates an encrypter for an APP_KEY (optionally "base64:"
+// prefixed) and a Laravel cipher name such as "AES-256-CBC". previousKeys are
+// only used to decrypt, like Laravel's app.previous_keys.
+func NewLaravelEncrypter(key, cipherName string, previousKeys ...string) (*LaravelEncrypter, error) {
+	cipherName = strings.ToLower(cipherName)
+	spec, ok := laravelCiphers[cipherName]
+	if !ok {
+		return nil, fmt.Errorf("laravel encrypter: unsupported cipher %q", cipherName)
+	}
+
+	decode := func(key string) ([]byte, error) {
+		raw := []byte(key)
+		if encoded, found := strings.CutPrefix(key, "base64:"); found {
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("laravel encrypter: invalid base64 key: %v", err)
+			}
+			raw = decoded
+		}
+		if len(raw) != spec.keySize {
+			return nil, fmt.Errorf("laravel encrypter: %s needs a %d byte key, got %d", cipherName, spec.keySize, len(raw))
+		}
+		return raw, nil
+	}
+
+	encrypter := &LaravelEncrypter{cipher: cipherName}
+	var err error
+	if encrypter.key, err = decode(key); err != nil {
+		return nil, err
+	}
+	for _, previous := range previousKeys {
+		decoded, err := decode(previous)
+		if err != nil {
+			return nil, err
+		}
+		encrypter.previousKeys = append(encrypter.previousKeys, decoded)
+	}
+	return encrypter, nil
+}
+
+// NewLaravelEncrypterFromEnv uses APP_KEY, APP_CIPHER (default AES-256-CBC) and
+// the comma separated APP_PREVIOUS_KEYS, like config/app.php.
+func NewLaravelEncrypterFromEnv() (*LaravelEncrypter, error) {
+	return NewLaravelEncrypter(GetEnv("APP_KEY", ""), GetEnv("APP_CIPHER", LaravelAES256CBC), previousKeysFromEnv()...)
+}
+
+// Encrypt is Crypt::encrypt: value is PHP serialized, then encrypted.
+func (e *LaravelEncrypter) Encrypt(value interface{}) (string, error) {
+	serialized, err := PHPSerialize(value)
+	if err != nil {
+		return "", err
+	}
+	return e.encrypt([]byte(serialized))
+}
+
+// EncryptString is Crypt::encryptString: value is encrypted without serialization.
+func (e *LaravelEncrypter) EncryptString(value string) (string, error) {
+	return e.encrypt([]byte(value))
+}
+
+// Decrypt is Crypt::decrypt: the payload is decrypted and PHP unserialized.
+// Malformed input never panics. Errors wrap ErrLaravelPayloadInvalid,
+// ErrLaravelMACInvalid or ErrLaravelDecrypt together with the matching
+// ErrDecryptBase64, ErrDecryptLength, ErrDecryptPadding, ErrDecryptMAC or
+// ErrDecryptSerialization.
+func (e *LaravelEncrypter) Decrypt(payload string) (interface{}, error) {
+	plaintext, err := e.decrypt(payload)
+	if err != nil {
+		return nil, err
+	}
+	value, err := PHPUnserialize(string(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrLaravelDecrypt, ErrDecryptSerialization, err)
+	}
+	return value, nil
+}
+
+// DecryptString is Crypt::decryptString. Errors are those of Decrypt.
+func (e *LaravelEncrypter) DecryptString(payload string) (string, error) {
+	plaintext, err := e.decrypt(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func (e *LaravelEncrypter) aead() bool {
+	return laravelCiphers[e.cipher].aead
+}
+
+func (e *LaravelEncrypter) encrypt(plaintext []byte) (string, error) {
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return "", err
+	}
+
+	var payload laravelPayload
+	if e.aead() {
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return "", err
+		}
+		iv := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(iv); err != nil {
+			return "", err
+		}
+		sealed := gcm.Seal(nil, iv, plaintext, nil)
+		split := len(sealed) - gcm.Overhead()
+		payload.IV = base64.StdEncoding.EncodeToString(iv)
+		payload.Value = base64.StdEncoding.EncodeToString(sealed[:split])
+		payload.Tag = base64.StdEncoding.EncodeToString(sealed[split:])
+	} else {
+		iv := make([]byte, aes.BlockSize)
+		if _, err := rand.Read(iv); err != nil {
+			return "", err
+		}
+		padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+		padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+		ciphertext := make([]byte, len(padded))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+		payload.IV = base64.StdEncoding.EncodeToString(iv)
+		payload.Value = base64.StdEncoding.EncodeToString(ciphertext)
+		payload.MAC = laravelMAC(payload.IV, payload.Value, e.key)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (e *LaravelEncrypter) decrypt(encoded string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLaravelPayloadInvalid, ErrDecryptBase64)
+	}
+	var payload laravelPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %w: not a JSON payload", ErrLaravelPayloadInvalid, ErrDecryptBase64)
+	}
+
+	iv, err := base64.StdEncoding.DecodeString(payload.IV)
+	if err != nil || payload.Value == "" {
+		return nil, fmt.Errorf("%w: %w: iv or value", ErrLaravelPayloadInvalid, ErrDecryptBase64)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(payload.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: value", ErrLaravelPayloadInvalid, ErrDecryptBase64)
+	}
+	tag, err := base64.StdEncoding.DecodeString(payload.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: tag", ErrLaravelPayloadInvalid, ErrDecryptBase64)
+	}
+
+	keys := append([][]byte{e.key}, e.previousKeys...)
+
+	if e.aead() {
+		if len(iv) != 12 || len(tag) != 16 {
+			return nil, fmt.Errorf("%w: %w: iv or tag", ErrLaravelPayloadInvalid, ErrDecryptLength)
+		}
+		sealed := append(append([]byte{}, ciphertext...), tag...)
+		for _, key := range keys {
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				continue
+			}
+			gcm, err := cipher.NewGCM(block)
+			if err != nil {
+				continue
+			}
+			if plaintext, err := gcm.Open(nil, iv, sealed, nil); err == nil {
+				return plaintext, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %w", ErrLaravelDecrypt, ErrDecryptMAC)
+	}
+
+	if len(iv) != aes.BlockSize || len(tag) != 0 {
+		return nil, fmt.Errorf("%w: %w: iv or tag", ErrLaravelPayloadInvalid, ErrDecryptLength)
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: %w: %d bytes is not a multiple of the block size", ErrLaravelDecrypt, ErrDecryptLength, len(ciphertext))
+	}
+
+	for _, key := range keys {
+		if !hmac.Equal([]byte(laravelMAC(payload.IV, payload.Value, key)), []byte(payload.MAC)) {
+			continue
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLaravelDecrypt, err)
+		}
+		plaintext := make([]byte, len(ciphertext))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+		unpadded, err := pkcs7Unpad(plaintext, aes.BlockSize)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrLaravelDecrypt, err)
+		}
+		return unpadded, nil
+	}
+	return nil, fmt.Errorf("%w: %w", ErrLaravelMACInvalid, ErrDecryptMAC)
+}
+
+// laravelMAC is hash_hmac('sha256', $iv.$value, $key) over the base64 strings.
+func laravelMAC(iv, value string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(iv + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pkcs7Unpad removes PKCS#7 padding. The whole last block is inspected so the
+// time taken does not depend on the padding length.
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	length := len(data)
+	if length == 0 || length%blockSize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a multiple of the block size", ErrDecryptLength, length)
+	}
+	padding := int(data[length-1])
+	good := subtle.ConstantTimeLessOrEq(1, padding) & subtle.ConstantTimeLessOrEq(padding, blockSize)
+	for i := 1; i <= blockSize; i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i, padding)
+		matches := subtle.ConstantTimeByteEq(data[length-i], byte(padding))
+		good &= subtle.ConstantTimeSelect(inPadding, matches, 1)
+	}
+	if good != 1 {
+		return nil, ErrDecryptPadding
+	}
+	return data[:length-padding], nil
+}
@@ -0,0 +1,156 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LaravelJobHandler is the "job" of every payload created by Laravel's Queue::push for job objects.
+const LaravelJobHandler = "Illuminate\\Queue\\CallQueuedHandler@call"
+
+// LaravelJob describes a queued job class of a Laravel service, e.g.
+// App\Jobs\SendApprovalMail with its constructor properties.
+type LaravelJob struct {
+	// Class is the fully qualified job class, e.g. "App\\Jobs\\SendApprovalMail".
+	Class string
+	// Properties are the job's public properties, set as if by its constructor.
+	Properties map[string]interface{}
+	// Queue overrides the queue name; empty uses LaravelQueue.DefaultQueue.
+	Queue string
+	// Delay makes the job available after the given duration.
+	Delay time.Duration
+
+	MaxTries      *int
+	MaxExceptions *int
+	Timeout       *int
+	Backoff       *string
+	RetryUntil    *int64
+	FailOnTimeout bool
+}
+
+// LaravelQueue pushes jobs into the Redis queues read by Laravel workers.
+type LaravelQueue struct {
+	Client *RedisClient
+	// Prefix is Laravel's database.redis.options.prefix, prepended to every key.
+	Prefix       string
+	DefaultQueue string
+	// Now overrides the clock, mainly for tests.
+	Now func() time.Time
+}
+
+// NewLaravelQueue creates a LaravelQueue writing to client with the given key prefix.
+func NewLaravelQueue(client *RedisClient, prefix string) *LaravelQueue {
+	return &LaravelQueue{
+		Client:       client,
+		Prefix:       prefix,
+		DefaultQueue: "default",
+		Now:          time.Now,
+	}
+}
+
+// NewLaravelQueueFromEnv configures the queue like a Laravel application does:
+// REDIS_PREFIX, defaulting to the slug of APP_NAME followed by "_database_",
+// and REDIS_QUEUE for the default queue name.
+func NewLaravelQueueFromEnv() *LaravelQueue {
+	prefix, ok := os.LookupEnv("REDIS_PREFIX")
+	if !ok {
+		prefix = laravelSlug(GetEnv("APP_NAME", "laravel")) + "_database_"
+	}
+	queue := NewLaravelQueue(NewRedisClientFromEnv(), prefix)
+	queue.DefaultQueue = GetEnv("REDIS_QUEUE", "default")
+	return queue
+}
+
+// Payload builds the JSON payload Laravel's RedisQueue would push for job.
+func (q *LaravelQueue) Payload(job LaravelJob) (string, []byte, error) {
+	if job.Class == "" {
+		return "", nil, errors.New("laravel queue: job class is required")
+	}
+
+	command, err := PHPSerialize(PHPObject{Class: job.Class, Properties: job.Properties})
+	if err != nil {
+		return "", nil, err
+	}
+	uuid, err := NewUUID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload := map[string]interface{}{
+		"uuid":          uuid,
+		"displayName":   job.Class,
+		"job":           LaravelJobHandler,
+		"maxTries":      job.MaxTries,
+		"maxExceptions": job.MaxExceptions,
+		"failOnTimeout": job.FailOnTimeout,
+		"backoff":       job.Backoff,
+		"timeout":       job.Timeout,
+		"retryUntil":    job.RetryUntil,
+		"data": map[string]interface{}{
+			"commandName": job.Class,
+			"command":     command,
+		},
+		"id":       uuid,
+		"attempts": 0,
+	}
+
+	data, err := json.Marshal(payload)
+	return uuid, data, err
+}
+
+// Dispatch pushes job and returns its UUID. Delayed jobs go to the
+// "<queue>:delayed" sorted set, the others to the "<queue>" list followed by a
+// "<queue>:notify" entry, matching Laravel's RedisQueue.
+func (q *LaravelQueue) Dispatch(ctx context.Context, job LaravelJob) (string, error) {
+	uuid, payload, err := q.Payload(job)
+	if err != nil {
+		return "", err
+	}
+
+	key := q.QueueKey(job.Queue)
+	if job.Delay > 0 {
+		availableAt := q.Now().Add(job.Delay).Unix()
+		_, err = q.Client.Do(ctx, "ZADD", key+":delayed", strconv.FormatInt(availableAt, 10), string(payload))
+		return uuid, err
+	}
+
+	if _, err := q.Client.Do(ctx, "RPUSH", key, string(payload)); err != nil {
+		return "", err
+	}
+	if _, err := q.Client.Do(ctx, "RPUSH", key+":notify", "1"); err != nil {
+		return "", err
+	}
+	return uuid, nil
+}
+
+// QueueKey returns the Redis key of queue, "<prefix>queues:<name>".
+func (q *LaravelQueue) QueueKey(queue string) string {
+	if queue == "" {
+		queue = q.DefaultQueue
+	}
+	if queue == "" {
+		queue = "default"
+	}
+	return q.Prefix + "queues:" + queue
+}
+
+var (
+	slugDashes  = regexp.MustCompile(`-+`)
+	slugInvalid = regexp.MustCompile(`[^_\pL\pN\s]+`)
+	slugSpaces  = regexp.MustCompile(`[_\s]+`)
+)
+
+// laravelSlug is Str::slug($value, '_') without the transliteration to ASCII:
+// dashes become separators, "@" becomes "at", other punctuation is dropped.
+func laravelSlug(value string) string {
+	value = slugDashes.ReplaceAllString(value, "_")
+	value = strings.ReplaceAll(value, "@", "_at_")
+	value = slugInvalid.ReplaceAllString(strings.ToLower(value), "")
+	return strings.Trim(slugSpaces.ReplaceAllString(value, "_"), "_")
+}
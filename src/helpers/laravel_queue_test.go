@@ -0,0 +1,122 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLaravelQueueDispatch(t *testing.T) {
+	server, client := newTestRedis(t)
+	queue := NewLaravelQueue(client, "sim_mbkm_database_")
+	now := time.Unix(1700000000, 0)
+	queue.Now = func() time.Time { return now }
+	tries := 3
+
+	uuid, err := queue.Dispatch(context.Background(), LaravelJob{
+		Class:      "App\\Jobs\\SendApprovalMail",
+		Properties: map[string]interface{}{"registrationId": 42},
+		MaxTries:   &tries,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := server.Commands()
+	if len(commands) != 2 {
+		t.Fatalf("commands = %q, want RPUSH and notify", commands)
+	}
+	if got := commands[0][:2]; !reflect.DeepEqual(got, []string{"RPUSH", "sim_mbkm_database_queues:default"}) {
+		t.Fatalf("first command = %q", got)
+	}
+	if got := commands[1]; !reflect.DeepEqual(got, []string{"RPUSH", "sim_mbkm_database_queues:default:notify", "1"}) {
+		t.Fatalf("second command = %q", got)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(commands[0][2]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"uuid":          uuid,
+		"id":            uuid,
+		"displayName":   "App\\Jobs\\SendApprovalMail",
+		"job":           LaravelJobHandler,
+		"maxTries":      float64(3),
+		"maxExceptions": nil,
+		"failOnTimeout": false,
+		"backoff":       nil,
+		"timeout":       nil,
+		"retryUntil":    nil,
+		"attempts":      float64(0),
+		"data": map[string]interface{}{
+			"commandName": "App\\Jobs\\SendApprovalMail",
+			"command":     `O:25:"App\Jobs\SendApprovalMail":1:{s:14:"registrationId";i:42;}`,
+		},
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Fatalf("payload = %v\nwant      %v", payload, want)
+	}
+	if list := server.List("sim_mbkm_database_queues:default"); len(list) != 1 {
+		t.Fatalf("queue holds %d jobs, want 1", len(list))
+	}
+}
+
+func TestLaravelQueueDispatchDelayed(t *testing.T) {
+	server, client := newTestRedis(t)
+	queue := NewLaravelQueue(client, "")
+	now := time.Unix(1700000000, 0)
+	queue.Now = func() time.Time { return now }
+
+	if _, err := queue.Dispatch(context.Background(), LaravelJob{Class: "App\\Jobs\\Remind", Queue: "mail", Delay: 90 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := server.Commands()
+	if len(commands) != 1 || commands[0][0] != "ZADD" || commands[0][1] != "queues:mail:delayed" || commands[0][2] != "1700000090" {
+		t.Fatalf("commands = %q, want ZADD queues:mail:delayed 1700000090 <payload>", commands)
+	}
+	if members := server.SortedSet("queues:mail:delayed"); len(members) != 1 {
+		t.Fatalf("delayed set = %v", members)
+	}
+	if list := server.List("queues:mail"); len(list) != 0 {
+		t.Fatalf("delayed job was pushed to the queue: %q", list)
+	}
+}
+
+func TestLaravelQueuePrefixFromEnv(t *testing.T) {
+	tests := []struct {
+		appName string
+		prefix  *string
+		want    string
+	}{
+		{"Laravel", nil, "laravel_database_queues:default"},
+		{"SIM MBKM", nil, "sim_mbkm_database_queues:default"},
+		{"  Registration-Service 2.0 ", nil, "registration_service_20_database_queues:default"},
+		{"Dosen@ITS  Portal", nil, "dosen_at_its_portal_database_queues:default"},
+		{"snake_case--app", nil, "snake_case_app_database_queues:default"},
+		{"SIM MBKM", ptr("custom:"), "custom:queues:default"},
+		{"SIM MBKM", ptr(""), "queues:default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.appName, func(t *testing.T) {
+			t.Setenv("APP_NAME", tt.appName)
+			t.Setenv("REDIS_PREFIX", "")
+			if tt.prefix != nil {
+				t.Setenv("REDIS_PREFIX", *tt.prefix)
+			} else {
+				os.Unsetenv("REDIS_PREFIX")
+			}
+			if got := NewLaravelQueueFromEnv().QueueKey(""); got != tt.want {
+				t.Fatalf("QueueKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
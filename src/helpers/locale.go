@@ -1,10 +1,15 @@
 package helpers
 
 import (
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 )
 
-// Locale helper struct to manage localization
+// Locale helper struct to manage localization.
+// The singleton only holds the process wide default; the locale of a request
+// lives in its context (see WithLocale and LocaleFromContext).
 type Locale struct {
 	mu     sync.RWMutex
 	locale string
@@ -35,3 +40,76 @@ func (l *Locale) SetLocale(locale string) {
 	defer l.mu.Unlock()
 	l.locale = locale
 }
+
+// NegotiateLocale picks the first supported locale from candidates, ordered by
+// preference. A regional candidate ("en-US") also matches its base language ("en").
+// When nothing matches, the first supported entry of fallback is returned, or "".
+func NegotiateLocale(candidates, supported, fallback []string) string {
+	index := make(map[string]string, len(supported))
+	for _, locale := range supported {
+		index[normalizeLocale(locale)] = locale
+	}
+
+	for _, candidate := range candidates {
+		candidate = normalizeLocale(candidate)
+		if candidate == "" {
+			continue
+		}
+		if locale, ok := index[candidate]; ok {
+			return locale
+		}
+		if base, _, found := strings.Cut(candidate, "-"); found {
+			if locale, ok := index[base]; ok {
+				return locale
+			}
+		}
+	}
+
+	for _, locale := range fallback {
+		if supported, ok := index[normalizeLocale(locale)]; ok {
+			return supported
+		}
+	}
+	return ""
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header ordered by quality.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality > 0 {
+			languages = append(languages, weighted{tag: tag, quality: quality})
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	result := make([]string, len(languages))
+	for i, language := range languages {
+		result[i] = language.tag
+	}
+	return result
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
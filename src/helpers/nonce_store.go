@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNonceStoreFull is returned by MemoryNonceStore when every slot holds a nonce
+// that has not expired yet.
+var ErrNonceStoreFull = errors.New("nonce store: full")
+
+// NonceStore remembers nonces for replay protection.
+type NonceStore interface {
+	// Remember records nonce for ttl and reports whether it was not seen before.
+	Remember(ctx context.Context, nonce string, ttl time.Duration) (fresh bool, err error)
+}
+
+// MemoryNonceStore is an in-process store of nonces with per entry expiry.
+// Live nonces are never evicted: when full, new nonces are rejected with
+// ErrNonceStoreFull until old ones expire, so size it for the expected request
+// rate times the validity window.
+type MemoryNonceStore struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type nonceEntry struct {
+	nonce     string
+	expiresAt time.Time
+}
+
+// NewMemoryNonceStore creates a store holding at most capacity nonces.
+func NewMemoryNonceStore(capacity int) *MemoryNonceStore {
+	if capacity <= 0 {
+		capacity = 100000
+	}
+	return &MemoryNonceStore{
+		capacity: capacity,
+		now:      time.Now,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Remember implements NonceStore.
+func (m *MemoryNonceStore) Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if element, ok := m.entries[nonce]; ok {
+		if element.Value.(*nonceEntry).expiresAt.After(now) {
+			return false, nil
+		}
+		m.remove(element)
+	}
+
+	// Entries are kept in insertion order, so with a fixed ttl the expired ones
+	// sit at the old end. Forgetting a live nonce would reopen it for replay.
+	for element := m.order.Back(); element != nil; element = m.order.Back() {
+		if element.Value.(*nonceEntry).expiresAt.After(now) {
+			break
+		}
+		m.remove(element)
+	}
+	if m.order.Len() >= m.capacity {
+		// Mixed ttls can leave expired entries behind live ones; sweep them all
+		// before giving up.
+		for element := m.order.Back(); element != nil; {
+			previous := element.Prev()
+			if !element.Value.(*nonceEntry).expiresAt.After(now) {
+				m.remove(element)
+			}
+			element = previous
+		}
+		if m.order.Len() >= m.capacity {
+			return false, ErrNonceStoreFull
+		}
+	}
+
+	m.entries[nonce] = m.order.PushFront(&nonceEntry{nonce: nonce, expiresAt: now.Add(ttl)})
+	return true, nil
+}
+
+// Len returns the number of remembered nonces.
+func (m *MemoryNonceStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *MemoryNonceStore) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*nonceEntry).nonce)
+}
+
+// RedisNonceStore shares seen nonces between instances using SET NX EX.
+type RedisNonceStore struct {
+	Client *RedisClient
+	Prefix string
+}
+
+// NewRedisNonceStore creates a RedisNonceStore storing keys as "<prefix><nonce>".
+func NewRedisNonceStore(client *RedisClient, prefix string) *RedisNonceStore {
+	return &RedisNonceStore{Client: client, Prefix: prefix}
+}
+
+// Remember implements NonceStore.
+func (r *RedisNonceStore) Remember(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	reply, err := r.Client.Do(ctx, "SET", r.Prefix+nonce, "1", "NX", "EX", strconv.FormatInt(seconds, 10))
+	if err != nil {
+		return false, err
+	}
+	// SET NX answers OK when the key was created and nil when it already existed.
+	return reply != nil, nil
+}
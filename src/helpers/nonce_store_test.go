@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryNonceStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryNonceStore(2)
+	store.now = func() time.Time { return now }
+
+	remember := func(nonce string, ttl time.Duration) (bool, error) {
+		t.Helper()
+		return store.Remember(ctx, nonce, ttl)
+	}
+
+	if fresh, err := remember("a", time.Minute); !fresh || err != nil {
+		t.Fatalf("first a = %v, %v", fresh, err)
+	}
+	if fresh, err := remember("a", time.Minute); fresh || err != nil {
+		t.Fatalf("replayed a = %v, %v", fresh, err)
+	}
+	if fresh, err := remember("b", 2*time.Minute); !fresh || err != nil {
+		t.Fatalf("first b = %v, %v", fresh, err)
+	}
+
+	// Full of live nonces: fail closed rather than forget a.
+	if _, err := remember("c", time.Minute); !errors.Is(err, ErrNonceStoreFull) {
+		t.Fatalf("full store error = %v, want ErrNonceStoreFull", err)
+	}
+	if fresh, _ := remember("a", time.Minute); fresh {
+		t.Fatal("a was evicted while still live")
+	}
+
+	// Once a expires its slot frees up and a may be used again.
+	now = now.Add(time.Minute)
+	if fresh, err := remember("c", time.Minute); !fresh || err != nil {
+		t.Fatalf("c after expiry = %v, %v", fresh, err)
+	}
+	if fresh, _ := remember("b", time.Minute); fresh {
+		t.Fatal("b expired early")
+	}
+	if store.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", store.Len())
+	}
+}
+
+func TestMemoryNonceStoreSweepsMixedTTLs(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryNonceStore(2)
+	store.now = func() time.Time { return now }
+
+	// The long lived entry is older, so the expired one is not at the old end.
+	store.Remember(ctx, "long", time.Hour)
+	store.Remember(ctx, "short", time.Second)
+	now = now.Add(time.Minute)
+
+	if fresh, err := store.Remember(ctx, "next", time.Minute); !fresh || err != nil {
+		t.Fatalf("Remember() = %v, %v", fresh, err)
+	}
+	if fresh, _ := store.Remember(ctx, "long", time.Hour); fresh {
+		t.Fatal("long was evicted while still live")
+	}
+}
+
+func TestRedisNonceStore(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedis(t)
+	store := NewRedisNonceStore(client, "nonce:")
+
+	if fresh, err := store.Remember(ctx, "a", 90*time.Second); !fresh || err != nil {
+		t.Fatalf("first a = %v, %v", fresh, err)
+	}
+	if fresh, err := store.Remember(ctx, "a", 90*time.Second); fresh || err != nil {
+		t.Fatalf("replayed a = %v, %v", fresh, err)
+	}
+	if ttl, err := client.Do(ctx, "TTL", "nonce:a"); err != nil || ttl != int64(90) {
+		t.Fatalf("TTL = %v, %v, want 90", ttl, err)
+	}
+
+	// Sub-second ttls are rounded up so the key is never stored without expiry.
+	if _, err := store.Remember(ctx, "b", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := client.Do(ctx, "TTL", "nonce:b"); ttl != int64(1) {
+		t.Fatalf("TTL b = %v, want 1", ttl)
+	}
+
+	server.FastForward(91 * time.Second)
+	if fresh, err := store.Remember(ctx, "a", 90*time.Second); !fresh || err != nil {
+		t.Fatalf("a after expiry = %v, %v", fresh, err)
+	}
+
+	server.Close()
+	if _, err := store.Remember(ctx, "c", time.Minute); err == nil {
+		t.Fatal("expected an error once Redis is gone")
+	}
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// PHPClass is implemented by structs that serialize as PHP objects
+// (O:...) instead of associative arrays.
+type PHPClass interface {
+	PHPClassName() string
+}
+
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	tag       reflect.StructTag
+}
+
+// phpStructFields lists the serialized fields of a struct type. Names come
+// from the `php` tag, then the `json` tag, then the Go field name; "-" skips a
+// field, ",omitempty" drops zero values and embedded structs are flattened.
+func phpStructFields(t reflect.Type) []structField {
+	return taggedStructFields(t, "php", "json")
+}
+
+// taggedStructFields lists the fields of a struct type named by the first of
+// tagNames present on each field. An embedded struct already being flattened,
+// as in type Node struct{ *Node }, is skipped.
+func taggedStructFields(t reflect.Type, tagNames ...string) []structField {
+	return collectStructFields(t, tagNames, map[reflect.Type]bool{})
+}
+
+func collectStructFields(t reflect.Type, tagNames []string, visiting map[reflect.Type]bool) []structField {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		var tag string
+		for _, tagName := range tagNames {
+			if value, ok := field.Tag.Lookup(tagName); ok {
+				tag = value
+				break
+			}
+		}
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if visiting[embedded] {
+					continue
+				}
+				for _, inner := range collectStructFields(embedded, tagNames, visiting) {
+					inner.index = append([]int{i}, inner.index...)
+					fields = append(fields, inner)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+			tag:       field.Tag,
+		})
+	}
+	return fields
+}
+
+// fieldByIndex walks index, returning false when it crosses a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, n := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(n)
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc walks index, allocating nil embedded pointers on the way.
+// Like encoding/json it fails on a nil pointer to an unexported embedded struct,
+// which reflection cannot set.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, n := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("%w: cannot set embedded pointer to unexported struct %s", ErrPHPSerialization, v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(n)
+	}
+	return v, nil
+}
@@ -0,0 +1,241 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PHPObject is a PHP object for serialization: O:<len>:"Class":<n>:{...}.
+// Property names follow PHP's mangling: "name" is public, "\x00*\x00name" is
+// protected and "\x00Class\x00name" is private.
+type PHPObject struct {
+	Class      string
+	Properties map[string]interface{}
+}
+
+// PHPSerialize encodes value like PHP's serialize(). Supported values are nil,
+// bool, integers, floats, strings, slices and arrays (PHP lists), maps with
+// string or integer keys (PHP arrays), PHPObject, and structs: associative
+// arrays keyed by their `php` (or `json`) tags, or objects when they implement PHPClass.
+func PHPSerialize(value interface{}) (string, error) {
+	var b strings.Builder
+	if err := phpEncode(&b, value); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func phpEncode(b *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		b.WriteString("N;")
+		return nil
+	case PHPObject:
+		return phpEncodeObject(b, v)
+	case *PHPObject:
+		if v == nil {
+			b.WriteString("N;")
+			return nil
+		}
+		return phpEncodeObject(b, *v)
+	}
+	if class, ok := value.(PHPClass); ok && reflect.ValueOf(value).Kind() == reflect.Struct {
+		return phpEncodeObject(b, PHPObject{Class: class.PHPClassName(), Properties: phpStructMap(reflect.ValueOf(value))})
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			b.WriteString("b:1;")
+		} else {
+			b.WriteString("b:0;")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprintf(b, "i:%d;", rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > math.MaxInt64 {
+			return fmt.Errorf("php serialize: %d overflows PHP int", rv.Uint())
+		}
+		fmt.Fprintf(b, "i:%d;", rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		b.WriteString("d:" + phpFormatFloat(rv.Float()) + ";")
+	case reflect.String:
+		phpEncodeString(b, rv.String())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			b.WriteString("N;")
+			return nil
+		}
+		fmt.Fprintf(b, "a:%d:{", rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			fmt.Fprintf(b, "i:%d;", i)
+			if err := phpEncode(b, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		b.WriteString("}")
+	case reflect.Map:
+		if rv.IsNil() {
+			b.WriteString("N;")
+			return nil
+		}
+		return phpEncodeMap(b, rv)
+	case reflect.Struct:
+		return phpEncodeStruct(b, rv)
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			b.WriteString("N;")
+			return nil
+		}
+		return phpEncode(b, rv.Elem().Interface())
+	default:
+		return fmt.Errorf("php serialize: unsupported type %T", value)
+	}
+	return nil
+}
+
+func phpEncodeMap(b *strings.Builder, rv reflect.Value) error {
+	keys := rv.MapKeys()
+	encoded := make([]string, len(keys))
+	for i, key := range keys {
+		switch key.Kind() {
+		case reflect.String:
+			encoded[i] = key.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			encoded[i] = strconv.FormatInt(key.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			encoded[i] = strconv.FormatUint(key.Uint(), 10)
+		default:
+			return fmt.Errorf("php serialize: unsupported map key type %s", key.Type())
+		}
+	}
+
+	// Go maps are unordered; sort keys so output is deterministic.
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return encoded[order[i]] < encoded[order[j]] })
+
+	fmt.Fprintf(b, "a:%d:{", len(keys))
+	for _, i := range order {
+		phpEncodeKey(b, encoded[i])
+		if err := phpEncode(b, rv.MapIndex(keys[i]).Interface()); err != nil {
+			return err
+		}
+	}
+	b.WriteString("}")
+	return nil
+}
+
+func phpEncodeObject(b *strings.Builder, object PHPObject) error {
+	names := make([]string, 0, len(object.Properties))
+	for name := range object.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(b, "O:%d:\"%s\":%d:{", len(object.Class), object.Class, len(names))
+	for _, name := range names {
+		phpEncodeString(b, name)
+		if err := phpEncode(b, object.Properties[name]); err != nil {
+			return err
+		}
+	}
+	b.WriteString("}")
+	return nil
+}
+
+// phpEncodeStruct writes a struct as an associative array in field order.
+func phpEncodeStruct(b *strings.Builder, rv reflect.Value) error {
+	type entry struct {
+		name  string
+		value interface{}
+	}
+
+	var entries []entry
+	for _, field := range phpStructFields(rv.Type()) {
+		value, ok := fieldByIndex(rv, field.index)
+		if !ok || (field.omitEmpty && value.IsZero()) {
+			continue
+		}
+		entries = append(entries, entry{field.name, value.Interface()})
+	}
+
+	fmt.Fprintf(b, "a:%d:{", len(entries))
+	for _, e := range entries {
+		phpEncodeKey(b, e.name)
+		if err := phpEncode(b, e.value); err != nil {
+			return err
+		}
+	}
+	b.WriteString("}")
+	return nil
+}
+
+// phpStructMap collects the serialized fields of a struct as object properties.
+func phpStructMap(rv reflect.Value) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, field := range phpStructFields(rv.Type()) {
+		value, ok := fieldByIndex(rv, field.index)
+		if !ok || (field.omitEmpty && value.IsZero()) {
+			continue
+		}
+		properties[field.name] = value.Interface()
+	}
+	return properties
+}
+
+// phpEncodeKey writes an array key; PHP stores canonical decimal strings as integers.
+func phpEncodeKey(b *strings.Builder, key string) {
+	if n, err := strconv.ParseInt(key, 10, 64); err == nil && strconv.FormatInt(n, 10) == key {
+		fmt.Fprintf(b, "i:%d;", n)
+		return
+	}
+	phpEncodeString(b, key)
+}
+
+// phpEncodeString writes s:<byte length>:"...";, as PHP counts bytes, not characters.
+func phpEncodeString(b *strings.Builder, s string) {
+	fmt.Fprintf(b, "s:%d:\"%s\";", len(s), s)
+}
+
+// phpFormatFloat formats like PHP with serialize_precision=-1: the shortest
+// representation, switching to "1.0E+25" style outside 1e-4 <= |v| < 1e15.
+func phpFormatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NAN"
+	case math.IsInf(v, 1):
+		return "INF"
+	case math.IsInf(v, -1):
+		return "-INF"
+	case v == 0:
+		if math.Signbit(v) {
+			return "-0"
+		}
+		return "0"
+	}
+
+	scientific := strconv.FormatFloat(v, 'e', -1, 64)
+	mantissa, exponentText, _ := strings.Cut(scientific, "e")
+	exponent, _ := strconv.Atoi(exponentText)
+	if exponent >= -4 && exponent < 15 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	sign := "+"
+	if exponent < 0 {
+		sign = "-"
+		exponent = -exponent
+	}
+	return fmt.Sprintf("%sE%s%d", mantissa, sign, exponent)
+}
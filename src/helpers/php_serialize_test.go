@@ -0,0 +1,130 @@
+package helpers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// phpSerializeCorpus holds output of PHP's serialize().
+var phpSerializeCorpus = []string{
+	`N;`,
+	`b:0;`,
+	`b:1;`,
+	`i:-42;`,
+	`d:0.1;`,
+	`d:-1.0E+25;`,
+	`d:INF;`,
+	`s:13:"héllo wörld";`,
+	`a:0:{}`,
+	`a:3:{i:0;i:1;i:1;d:2.5;i:2;b:1;}`,
+	`a:2:{s:4:"name";s:5:"Alice";s:3:"age";i:30;}`,
+	`a:2:{i:5;s:4:"five";s:1:"x";N;}`,
+	`a:1:{s:5:"items";a:2:{i:0;a:1:{s:2:"id";i:1;}i:1;a:1:{s:2:"id";i:2;}}}`,
+	`O:8:"stdClass":1:{s:3:"foo";s:3:"bar";}`,
+	"O:3:\"Foo\":2:{s:4:\"\x00*\x00a\";i:1;s:6:\"\x00Foo\x00b\";i:2;}",
+	"O:29:\"Illuminate\\Support\\Collection\":1:{s:8:\"\x00*\x00items\";a:0:{}}",
+}
+
+// FuzzPHPRoundTrip checks that anything PHPUnserialize accepts serializes back
+// to data that decodes to the same value.
+func FuzzPHPRoundTrip(f *testing.F) {
+	for _, seed := range phpSerializeCorpus {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data string) {
+		value, err := PHPUnserialize(data)
+		if err != nil {
+			if !errors.Is(err, ErrPHPSerialization) {
+				t.Fatalf("PHPUnserialize(%q) error %v does not wrap ErrPHPSerialization", data, err)
+			}
+			return
+		}
+		first, err := PHPSerialize(value)
+		if err != nil {
+			t.Fatalf("PHPSerialize(%#v): %v", value, err)
+		}
+		decoded, err := PHPUnserialize(first)
+		if err != nil {
+			t.Fatalf("PHPUnserialize(%q) of serialized output: %v", first, err)
+		}
+		second, err := PHPSerialize(decoded)
+		if err != nil {
+			t.Fatalf("PHPSerialize(%#v): %v", decoded, err)
+		}
+		if first != second {
+			t.Fatalf("round trip changed %q into %q", first, second)
+		}
+	})
+}
+
+type phpRoundTripUser struct {
+	Name   string   `php:"name"`
+	Age    int64    `php:"age"`
+	Score  float64  `php:"score"`
+	Active bool     `php:"active"`
+	Tags   []string `php:"tags"`
+}
+
+func FuzzPHPStructRoundTrip(f *testing.F) {
+	f.Add("Alice", int64(30), 2.5, true, "admin")
+	f.Add("", int64(-1), 0.0, false, "")
+	f.Add("héllo \x00 \"quoted\";", int64(1<<62), -1e25, true, "a;b")
+	f.Fuzz(func(t *testing.T, name string, age int64, score float64, active bool, tag string) {
+		if score != score {
+			t.Skip("NaN never compares equal")
+		}
+		in := phpRoundTripUser{Name: name, Age: age, Score: score, Active: active, Tags: []string{tag}}
+		data, err := PHPSerialize(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out phpRoundTripUser
+		if err := PHPUnmarshal(data, &out); err != nil {
+			t.Fatalf("PHPUnmarshal(%q): %v", data, err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Fatalf("round trip of %q: got %#v, want %#v", data, out, in)
+		}
+	})
+}
+
+type phpInner struct {
+	Value string `php:"value"`
+}
+
+type phpEmbedsUnexported struct {
+	*phpInner
+	Name string `php:"name"`
+}
+
+type phpNode struct {
+	*phpNode
+	ID int `php:"id"`
+}
+
+func TestPHPUnmarshalEmbeddedUnexportedPointer(t *testing.T) {
+	var out phpEmbedsUnexported
+	err := PHPUnmarshal(`a:2:{s:5:"value";s:1:"v";s:4:"name";s:1:"n";}`, &out)
+	if !errors.Is(err, ErrPHPSerialization) {
+		t.Fatalf("error = %v, want ErrPHPSerialization", err)
+	}
+
+	if err := PHPUnmarshal(`a:1:{s:4:"name";s:1:"n";}`, &out); err != nil || out.Name != "n" {
+		t.Fatalf("got %#v, %v", out, err)
+	}
+}
+
+func TestPHPRecursiveEmbeddedStruct(t *testing.T) {
+	data, err := PHPSerialize(phpNode{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != `a:1:{s:2:"id";i:1;}` {
+		t.Fatalf("PHPSerialize = %q", data)
+	}
+	var out phpNode
+	if err := PHPUnmarshal(data, &out); err != nil || out.ID != 1 {
+		t.Fatalf("got %#v, %v", out, err)
+	}
+}
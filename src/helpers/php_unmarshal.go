@@ -0,0 +1,245 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PHPUnmarshal decodes PHP serialized data into the value pointed to by v.
+// Struct fields are matched by their `php` (or `json`) tag, then by name
+// case-insensitively; PHP objects and associative arrays both fill structs
+// and maps. Numeric strings convert to numbers the way PHP compares them.
+func PHPUnmarshal(data string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("php unmarshal: non-nil pointer required, got %T", v)
+	}
+
+	decoded, err := PHPUnserialize(data)
+	if err != nil {
+		return err
+	}
+	return phpAssign(rv.Elem(), decoded, "")
+}
+
+func phpAssignError(dst reflect.Value, src interface{}, path string) error {
+	if path == "" {
+		path = "value"
+	}
+	return fmt.Errorf("%w: cannot assign %T to %s (%s)", ErrPHPSerialization, src, dst.Type(), path)
+}
+
+func phpAssign(dst reflect.Value, src interface{}, path string) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		if dst.NumMethod() != 0 {
+			return phpAssignError(dst, src, path)
+		}
+		dst.Set(reflect.ValueOf(src))
+		return nil
+
+	case reflect.Pointer:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return phpAssign(dst.Elem(), src, path)
+
+	case reflect.Bool:
+		switch s := src.(type) {
+		case bool:
+			dst.SetBool(s)
+		case int64:
+			dst.SetBool(s != 0)
+		default:
+			return phpAssignError(dst, src, path)
+		}
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := phpToInt(src)
+		if !ok || dst.OverflowInt(n) {
+			return phpAssignError(dst, src, path)
+		}
+		dst.SetInt(n)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := phpToInt(src)
+		if !ok || n < 0 || dst.OverflowUint(uint64(n)) {
+			return phpAssignError(dst, src, path)
+		}
+		dst.SetUint(uint64(n))
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch s := src.(type) {
+		case float64:
+			f = s
+		case int64:
+			f = float64(s)
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return phpAssignError(dst, src, path)
+			}
+			f = parsed
+		default:
+			return phpAssignError(dst, src, path)
+		}
+		if dst.OverflowFloat(f) {
+			return phpAssignError(dst, src, path)
+		}
+		dst.SetFloat(f)
+		return nil
+
+	case reflect.String:
+		switch s := src.(type) {
+		case string:
+			dst.SetString(s)
+		case int64:
+			dst.SetString(strconv.FormatInt(s, 10))
+		case float64:
+			dst.SetString(phpFormatFloat(s))
+		default:
+			return phpAssignError(dst, src, path)
+		}
+		return nil
+
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			if s, ok := src.(string); ok {
+				dst.SetBytes([]byte(s))
+				return nil
+			}
+		}
+		list, ok := src.([]interface{})
+		if !ok {
+			return phpAssignError(dst, src, path)
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(list), len(list))
+		for i, item := range list {
+			if err := phpAssign(slice.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+		return nil
+
+	case reflect.Array:
+		list, ok := src.([]interface{})
+		if !ok || len(list) > dst.Len() {
+			return phpAssignError(dst, src, path)
+		}
+		for i := 0; i < dst.Len(); i++ {
+			var item interface{}
+			if i < len(list) {
+				item = list[i]
+			}
+			if err := phpAssign(dst.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		entries, ok := phpEntries(src)
+		if !ok {
+			return phpAssignError(dst, src, path)
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), len(entries))
+		for key, item := range entries {
+			mapKey := reflect.New(dst.Type().Key()).Elem()
+			if err := phpAssign(mapKey, key, path); err != nil {
+				return err
+			}
+			mapValue := reflect.New(dst.Type().Elem()).Elem()
+			if err := phpAssign(mapValue, item, path+"."+key); err != nil {
+				return err
+			}
+			m.SetMapIndex(mapKey, mapValue)
+		}
+		dst.Set(m)
+		return nil
+
+	case reflect.Struct:
+		entries, ok := src.(map[string]interface{})
+		if !ok {
+			return phpAssignError(dst, src, path)
+		}
+		for _, field := range phpStructFields(dst.Type()) {
+			item, found := entries[field.name]
+			if !found {
+				for key, candidate := range entries {
+					if strings.EqualFold(key, field.name) {
+						item, found = candidate, true
+						break
+					}
+				}
+			}
+			if !found {
+				continue
+			}
+			fieldPath := field.name
+			if path != "" {
+				fieldPath = path + "." + field.name
+			}
+			target, err := fieldByIndexAlloc(dst, field.index)
+			if err != nil {
+				return err
+			}
+			if err := phpAssign(target, item, fieldPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return phpAssignError(dst, src, path)
+}
+
+// phpToInt converts a decoded scalar to an integer; floats must be integral.
+func phpToInt(src interface{}) (int64, bool) {
+	switch s := src.(type) {
+	case int64:
+		return s, true
+	case bool:
+		if s {
+			return 1, true
+		}
+		return 0, true
+	case float64:
+		if s != math.Trunc(s) || math.IsInf(s, 0) || s < math.MinInt64 || s >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(s), true
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// phpEntries views a decoded array as string-keyed entries; lists are keyed
+// by their index.
+func phpEntries(src interface{}) (map[string]interface{}, bool) {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		return s, true
+	case []interface{}:
+		entries := make(map[string]interface{}, len(s))
+		for i, item := range s {
+			entries[strconv.Itoa(i)] = item
+		}
+		return entries, true
+	}
+	return nil, false
+}
@@ -0,0 +1,275 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrPHPSerialization is wrapped by every PHPUnserialize error.
+var ErrPHPSerialization = errors.New("php unserialize: invalid data")
+
+// PHPUnserialize decodes a value produced by PHP's serialize(). Results are nil,
+// bool, int64, float64, string, []interface{} for arrays with keys 0..n-1,
+// map[string]interface{} for other arrays and map[string]interface{} for
+// objects, with property names unmangled.
+func PHPUnserialize(data string) (interface{}, error) {
+	decoder := &phpDecoder{data: data}
+	value, err := decoder.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.pos != len(data) {
+		return nil, decoder.errorf("trailing data")
+	}
+	return value, nil
+}
+
+// phpMaxDepth bounds nesting so hostile input cannot exhaust the stack.
+const phpMaxDepth = 512
+
+type phpDecoder struct {
+	data string
+	pos  int
+}
+
+func (d *phpDecoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrPHPSerialization, d.pos, fmt.Sprintf(format, args...))
+}
+
+func (d *phpDecoder) expect(s string) error {
+	if !strings.HasPrefix(d.data[d.pos:], s) {
+		return d.errorf("expected %q", s)
+	}
+	d.pos += len(s)
+	return nil
+}
+
+// until returns the text up to delim and moves past it.
+func (d *phpDecoder) until(delim byte) (string, error) {
+	end := strings.IndexByte(d.data[d.pos:], delim)
+	if end < 0 {
+		return "", d.errorf("expected %q", delim)
+	}
+	text := d.data[d.pos : d.pos+end]
+	d.pos += end + 1
+	return text, nil
+}
+
+func (d *phpDecoder) integer(delim byte) (int64, error) {
+	text, err := d.until(delim)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, d.errorf("invalid integer %q", text)
+	}
+	return n, nil
+}
+
+// length reads a non negative count that must fit in the remaining input.
+func (d *phpDecoder) length(delim byte) (int, error) {
+	n, err := d.integer(delim)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > int64(len(d.data)-d.pos) {
+		return 0, d.errorf("invalid length %d", n)
+	}
+	return int(n), nil
+}
+
+// str reads the `<len>:"<bytes>"` part of strings and class names. The length
+// counts bytes, so the content may contain quotes, semicolons or UTF-8.
+func (d *phpDecoder) str() (string, error) {
+	n, err := d.length(':')
+	if err != nil {
+		return "", err
+	}
+	if err := d.expect(`"`); err != nil {
+		return "", err
+	}
+	if d.pos+n > len(d.data) {
+		return "", d.errorf("string overruns input")
+	}
+	s := d.data[d.pos : d.pos+n]
+	d.pos += n
+	if err := d.expect(`"`); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func (d *phpDecoder) value(depth int) (interface{}, error) {
+	if depth > phpMaxDepth {
+		return nil, d.errorf("nesting too deep")
+	}
+	if d.pos+2 > len(d.data) {
+		return nil, d.errorf("unexpected end of input")
+	}
+
+	kind := d.data[d.pos]
+	if kind == 'N' {
+		d.pos++
+		return nil, d.expect(";")
+	}
+	d.pos++
+	if err := d.expect(":"); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case 'b':
+		text, err := d.until(';')
+		if err != nil {
+			return nil, err
+		}
+		switch text {
+		case "0":
+			return false, nil
+		case "1":
+			return true, nil
+		}
+		return nil, d.errorf("invalid bool %q", text)
+	case 'i':
+		return d.integer(';')
+	case 'd':
+		text, err := d.until(';')
+		if err != nil {
+			return nil, err
+		}
+		switch text {
+		case "INF":
+			return math.Inf(1), nil
+		case "-INF":
+			return math.Inf(-1), nil
+		case "NAN":
+			return math.NaN(), nil
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, d.errorf("invalid float %q", text)
+		}
+		return f, nil
+	case 's':
+		s, err := d.str()
+		if err != nil {
+			return nil, err
+		}
+		return s, d.expect(";")
+	case 'a':
+		return d.array(depth)
+	case 'O':
+		return d.object(depth)
+	}
+	return nil, d.errorf("unsupported type %q", kind)
+}
+
+func (d *phpDecoder) key() (string, error) {
+	if d.pos >= len(d.data) {
+		return "", d.errorf("unexpected end of input")
+	}
+	switch d.data[d.pos] {
+	case 'i':
+		d.pos++
+		if err := d.expect(":"); err != nil {
+			return "", err
+		}
+		n, err := d.integer(';')
+		return strconv.FormatInt(n, 10), err
+	case 's':
+		d.pos++
+		if err := d.expect(":"); err != nil {
+			return "", err
+		}
+		s, err := d.str()
+		if err != nil {
+			return "", err
+		}
+		return s, d.expect(";")
+	}
+	return "", d.errorf("invalid array key")
+}
+
+// members reads `<n>:{<key><value>...}` into ordered keys and values.
+func (d *phpDecoder) members(depth int) ([]string, []interface{}, error) {
+	n, err := d.length(':')
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := d.expect("{"); err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]string, 0, n)
+	values := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		key, err := d.key()
+		if err != nil {
+			return nil, nil, err
+		}
+		value, err := d.value(depth + 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values, d.expect("}")
+}
+
+func (d *phpDecoder) array(depth int) (interface{}, error) {
+	keys, values, err := d.members(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	isList := true
+	for i, key := range keys {
+		if key != strconv.Itoa(i) {
+			isList = false
+			break
+		}
+	}
+	if isList {
+		return values, nil
+	}
+
+	result := make(map[string]interface{}, len(keys))
+	for i, key := range keys {
+		result[key] = values[i]
+	}
+	return result, nil
+}
+
+func (d *phpDecoder) object(depth int) (interface{}, error) {
+	if _, err := d.str(); err != nil {
+		return nil, err
+	}
+	if err := d.expect(":"); err != nil {
+		return nil, err
+	}
+	keys, values, err := d.members(depth)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(keys))
+	for i, key := range keys {
+		result[phpPropertyName(key)] = values[i]
+	}
+	return result, nil
+}
+
+// phpPropertyName removes the "\x00*\x00" and "\x00Class\x00" visibility mangling.
+func phpPropertyName(name string) string {
+	if strings.HasPrefix(name, "\x00") {
+		if end := strings.IndexByte(name[1:], 0); end >= 0 {
+			return name[end+2:]
+		}
+	}
+	return name
+}
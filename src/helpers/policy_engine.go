@@ -0,0 +1,436 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy rule effects.
+const (
+	PolicyAllow = "allow"
+	PolicyDeny  = "deny"
+)
+
+// Combining algorithms of a PolicyEngine.
+const (
+	// PolicyDenyOverrides denies when any matching rule denies, otherwise
+	// allows when any matching rule allows.
+	PolicyDenyOverrides = "deny_overrides"
+	// PolicyFirstMatch takes the effect of the first matching rule.
+	PolicyFirstMatch = "first_match"
+)
+
+// PolicyCaller is the calling service in a PolicyInput.
+type PolicyCaller struct {
+	ID     string
+	Scopes []string
+	Shared bool
+}
+
+// PolicyUser is the end user in a PolicyInput.
+type PolicyUser struct {
+	ID          string
+	Roles       []string
+	Permissions []string
+	Attributes  map[string]interface{}
+}
+
+// PolicyInput is everything a policy decision is made on. Conditions address it
+// with dotted attribute names: caller.id, caller.scopes, caller.shared, user.id,
+// user.roles, user.permissions, user.attributes.<name>, action, method, path,
+// route, params.<name> and resource.<name>.
+type PolicyInput struct {
+	Caller   *PolicyCaller
+	User     *PolicyUser
+	Action   string
+	Method   string
+	Path     string
+	Route    string
+	Params   map[string]string
+	Resource map[string]interface{}
+}
+
+// PolicyCondition compares an attribute with a literal Value or with another
+// attribute named by Ref. Operators: eq, ne, in, not_in, contains, exists.
+// A missing attribute fails every operator but exists, except that ne and
+// not_in hold in deny rules: leaving an attribute out must not dodge a denial.
+type PolicyCondition struct {
+	Attr  string      `json:"attr" yaml:"attr"`
+	Op    string      `json:"op" yaml:"op"`
+	Value interface{} `json:"value" yaml:"value"`
+	Ref   string      `json:"ref" yaml:"ref"`
+}
+
+// PolicyRule applies Effect when the action, route and method match and every
+// condition in When holds. Empty Actions, Routes or Methods match anything.
+type PolicyRule struct {
+	ID          string            `json:"id" yaml:"id"`
+	Description string            `json:"description" yaml:"description"`
+	Effect      string            `json:"effect" yaml:"effect"`
+	Actions     []string          `json:"actions" yaml:"actions"`
+	Routes      []string          `json:"routes" yaml:"routes"`
+	Methods     []string          `json:"methods" yaml:"methods"`
+	When        []PolicyCondition `json:"when" yaml:"when"`
+}
+
+// PolicyDocument is the content of a policy file.
+type PolicyDocument struct {
+	// Algorithm is PolicyDenyOverrides (the default) or PolicyFirstMatch.
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	// Default is the effect when no rule matches, deny unless set to allow.
+	Default string       `json:"default" yaml:"default"`
+	Rules   []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+// PolicyTrace records how one rule was evaluated.
+type PolicyTrace struct {
+	RuleID  string
+	Matched bool
+	// Failed explains why the rule did not match.
+	Failed string
+}
+
+// PolicyDecision is the outcome of an evaluation, with enough detail to explain it.
+type PolicyDecision struct {
+	Allowed bool
+	// RuleID is the rule that decided, empty when the default applied.
+	RuleID string
+	Reason string
+	Trace  []PolicyTrace
+}
+
+// Explain renders the decision and the evaluation of every rule for logs.
+func (d PolicyDecision) Explain() string {
+	var b strings.Builder
+	b.WriteString(d.Reason)
+	for _, trace := range d.Trace {
+		if trace.Matched {
+			fmt.Fprintf(&b, "; %s: matched", trace.RuleID)
+		} else {
+			fmt.Fprintf(&b, "; %s: %s", trace.RuleID, trace.Failed)
+		}
+	}
+	return b.String()
+}
+
+// PolicyEngine evaluates attribute based rules.
+type PolicyEngine struct {
+	document PolicyDocument
+}
+
+// NewPolicyEngine validates document and creates an engine for it.
+func NewPolicyEngine(document PolicyDocument) (*PolicyEngine, error) {
+	switch document.Algorithm {
+	case "":
+		document.Algorithm = PolicyDenyOverrides
+	case PolicyDenyOverrides, PolicyFirstMatch:
+	default:
+		return nil, fmt.Errorf("policy: unknown algorithm %q", document.Algorithm)
+	}
+	switch document.Default {
+	case "":
+		document.Default = PolicyDeny
+	case PolicyAllow, PolicyDeny:
+	default:
+		return nil, fmt.Errorf("policy: default must be allow or deny, got %q", document.Default)
+	}
+
+	seen := map[string]bool{}
+	for i := range document.Rules {
+		rule := &document.Rules[i]
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i+1)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("policy: duplicate rule id %q", rule.ID)
+		}
+		seen[rule.ID] = true
+		if rule.Effect != PolicyAllow && rule.Effect != PolicyDeny {
+			return nil, fmt.Errorf("policy: rule %s: effect must be allow or deny", rule.ID)
+		}
+		for _, condition := range rule.When {
+			if !validPolicyOperators[condition.Op] {
+				return nil, fmt.Errorf("policy: rule %s: unknown operator %q", rule.ID, condition.Op)
+			}
+			if condition.Attr == "" {
+				return nil, fmt.Errorf("policy: rule %s: condition without attr", rule.ID)
+			}
+		}
+	}
+	return &PolicyEngine{document: document}, nil
+}
+
+// LoadPolicyEngine reads rules from JSON or YAML files; directories contribute
+// every .json, .yaml and .yml file in name order. The algorithm and default of
+// the first file that sets them apply.
+func LoadPolicyEngine(paths ...string) (*PolicyEngine, error) {
+	var files []string
+	for _, location := range paths {
+		info, err := os.Stat(location)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, location)
+			continue
+		}
+		entries, err := os.ReadDir(location)
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".json", ".yaml", ".yml":
+				names = append(names, filepath.Join(location, entry.Name()))
+			}
+		}
+		sort.Strings(names)
+		files = append(files, names...)
+	}
+
+	var merged PolicyDocument
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var document PolicyDocument
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(data, &document)
+		default:
+			err = json.Unmarshal(data, &document)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy %s: %v", file, err)
+		}
+		if merged.Algorithm == "" {
+			merged.Algorithm = document.Algorithm
+		}
+		if merged.Default == "" {
+			merged.Default = document.Default
+		}
+		merged.Rules = append(merged.Rules, document.Rules...)
+	}
+	return NewPolicyEngine(merged)
+}
+
+// Can reports whether input is allowed.
+func (e *PolicyEngine) Can(input PolicyInput) bool {
+	return e.Decide(input).Allowed
+}
+
+// Decide evaluates every rule against input.
+func (e *PolicyEngine) Decide(input PolicyInput) PolicyDecision {
+	attributes := input.attributes()
+	decision := PolicyDecision{}
+	var allowedBy, deniedBy string
+
+	for i := range e.document.Rules {
+		rule := &e.document.Rules[i]
+		failed := rule.mismatch(input, attributes)
+		decision.Trace = append(decision.Trace, PolicyTrace{RuleID: rule.ID, Matched: failed == "", Failed: failed})
+		if failed != "" {
+			continue
+		}
+
+		if e.document.Algorithm == PolicyFirstMatch {
+			decision.Allowed = rule.Effect == PolicyAllow
+			decision.RuleID = rule.ID
+			decision.Reason = fmt.Sprintf("%s by rule %s", rule.Effect, rule.ID)
+			return decision
+		}
+		if rule.Effect == PolicyDeny && deniedBy == "" {
+			deniedBy = rule.ID
+		}
+		if rule.Effect == PolicyAllow && allowedBy == "" {
+			allowedBy = rule.ID
+		}
+	}
+
+	switch {
+	case deniedBy != "":
+		decision.RuleID = deniedBy
+		decision.Reason = fmt.Sprintf("denied by rule %s", deniedBy)
+	case allowedBy != "":
+		decision.Allowed = true
+		decision.RuleID = allowedBy
+		decision.Reason = fmt.Sprintf("allowed by rule %s", allowedBy)
+	default:
+		decision.Allowed = e.document.Default == PolicyAllow
+		decision.Reason = fmt.Sprintf("no rule matched, default %s", e.document.Default)
+	}
+	return decision
+}
+
+// mismatch returns why rule does not apply to input, or "" when it does.
+func (r *PolicyRule) mismatch(input PolicyInput, attributes map[string]interface{}) string {
+	if len(r.Actions) > 0 && !matchesAnyGlob(r.Actions, input.Action) {
+		return fmt.Sprintf("action %q not in %v", input.Action, r.Actions)
+	}
+	if len(r.Methods) > 0 && !containsFold(r.Methods, input.Method) && !containsValue(r.Methods, "*") {
+		return fmt.Sprintf("method %s not in %v", input.Method, r.Methods)
+	}
+	if len(r.Routes) > 0 {
+		matched := false
+		for _, pattern := range r.Routes {
+			if pattern == input.Route || MatchRoutePattern(pattern, input.Path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("path %s not in %v", input.Path, r.Routes)
+		}
+	}
+	for _, condition := range r.When {
+		if ok, why := condition.holds(attributes, r.Effect); !ok {
+			return why
+		}
+	}
+	return ""
+}
+
+func matchesAnyGlob(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+var validPolicyOperators = map[string]bool{
+	"eq": true, "ne": true, "in": true, "not_in": true, "contains": true, "exists": true,
+}
+
+func (c PolicyCondition) holds(attributes map[string]interface{}, effect string) (bool, string) {
+	actual, found := lookupAttribute(attributes, c.Attr)
+	if c.Op == "exists" {
+		if found && actual != nil {
+			return true, ""
+		}
+		return false, fmt.Sprintf("%s does not exist", c.Attr)
+	}
+
+	missing := ""
+	expected := c.Value
+	if c.Ref != "" {
+		var ok bool
+		if expected, ok = lookupAttribute(attributes, c.Ref); !ok || expected == nil {
+			missing = c.Ref
+		}
+	}
+	if !found || actual == nil {
+		missing = c.Attr
+	}
+	if missing != "" {
+		if effect == PolicyDeny && (c.Op == "ne" || c.Op == "not_in") {
+			return true, ""
+		}
+		return false, fmt.Sprintf("%s does not exist", missing)
+	}
+
+	var ok bool
+	switch c.Op {
+	case "eq":
+		ok = policyEqual(actual, expected)
+	case "ne":
+		ok = !policyEqual(actual, expected)
+	case "in":
+		ok = policyContains(expected, actual)
+	case "not_in":
+		ok = !policyContains(expected, actual)
+	case "contains":
+		ok = policyContains(actual, expected)
+	}
+	if ok {
+		return true, ""
+	}
+	if c.Ref != "" {
+		return false, fmt.Sprintf("%s %s %s failed (%v vs %v)", c.Attr, c.Op, c.Ref, actual, expected)
+	}
+	return false, fmt.Sprintf("%s %s %v failed (got %v)", c.Attr, c.Op, expected, actual)
+}
+
+// policyEqual compares scalars by their ClaimString form, so the route
+// parameter "5" equals the number 5 from a resource and large ids are not
+// written in e-notation.
+func policyEqual(a, b interface{}) bool {
+	return ClaimString(a) == ClaimString(b)
+}
+
+func policyContains(list, value interface{}) bool {
+	switch items := list.(type) {
+	case []string:
+		for _, item := range items {
+			if policyEqual(item, value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range items {
+			if policyEqual(item, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (in PolicyInput) attributes() map[string]interface{} {
+	params := map[string]interface{}{}
+	for name, value := range in.Params {
+		params[name] = value
+	}
+	attributes := map[string]interface{}{
+		"action":   in.Action,
+		"method":   in.Method,
+		"path":     in.Path,
+		"route":    in.Route,
+		"params":   params,
+		"resource": in.Resource,
+	}
+	if in.Caller != nil {
+		attributes["caller"] = map[string]interface{}{
+			"id":     in.Caller.ID,
+			"scopes": in.Caller.Scopes,
+			"shared": in.Caller.Shared,
+		}
+	}
+	if in.User != nil {
+		attributes["user"] = map[string]interface{}{
+			"id":          in.User.ID,
+			"roles":       in.User.Roles,
+			"permissions": in.User.Permissions,
+			"attributes":  in.User.Attributes,
+		}
+	}
+	return attributes
+}
+
+// lookupAttribute resolves a dotted name in nested maps.
+func lookupAttribute(attributes map[string]interface{}, name string) (interface{}, bool) {
+	var current interface{} = attributes
+	for _, part := range strings.Split(name, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
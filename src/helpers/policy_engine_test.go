@@ -0,0 +1,198 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestPolicyEngine(t *testing.T, document PolicyDocument) *PolicyEngine {
+	t.Helper()
+	engine, err := NewPolicyEngine(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestPolicyEngineDecide(t *testing.T) {
+	rules := []PolicyRule{
+		{ID: "lecturers-read", Effect: PolicyAllow, Actions: []string{"grades.*"}, When: []PolicyCondition{{Attr: "user.roles", Op: "contains", Value: "lecturer"}}},
+		{ID: "own-grades", Effect: PolicyAllow, Actions: []string{"grades.read"}, When: []PolicyCondition{{Attr: "user.id", Op: "eq", Ref: "resource.student_id"}}},
+		{ID: "locked", Effect: PolicyDeny, Actions: []string{"grades.update"}, When: []PolicyCondition{{Attr: "resource.status", Op: "eq", Value: "locked"}}},
+	}
+	lecturer := &PolicyUser{ID: "7", Roles: []string{"lecturer"}}
+	student := &PolicyUser{ID: "5025201001"}
+
+	tests := []struct {
+		name      string
+		algorithm string
+		input     PolicyInput
+		allowed   bool
+		ruleID    string
+	}{
+		{"allow", "", PolicyInput{Action: "grades.update", User: lecturer, Resource: map[string]interface{}{"status": "open"}}, true, "lecturers-read"},
+		{"deny overrides allow", "", PolicyInput{Action: "grades.update", User: lecturer, Resource: map[string]interface{}{"status": "locked"}}, false, "locked"},
+		{"first match allows", PolicyFirstMatch, PolicyInput{Action: "grades.update", User: lecturer, Resource: map[string]interface{}{"status": "locked"}}, true, "lecturers-read"},
+		// Decoded JSON numbers are float64; a large id must not become 5.025201001e+09.
+		{"ref to a number", "", PolicyInput{Action: "grades.read", User: student, Resource: map[string]interface{}{"student_id": float64(5025201001)}}, true, "own-grades"},
+		{"ref mismatch", "", PolicyInput{Action: "grades.read", User: student, Resource: map[string]interface{}{"student_id": float64(5025201002)}}, false, ""},
+		{"default deny", "", PolicyInput{Action: "students.read", User: lecturer}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestPolicyEngine(t, PolicyDocument{Algorithm: tt.algorithm, Rules: rules})
+			decision := engine.Decide(tt.input)
+			if decision.Allowed != tt.allowed || decision.RuleID != tt.ruleID {
+				t.Fatalf("decision = %+v, want allowed %v by %q", decision, tt.allowed, tt.ruleID)
+			}
+			if engine.Can(tt.input) != tt.allowed {
+				t.Fatal("Can() disagrees with Decide()")
+			}
+			if len(decision.Trace) == 0 || !strings.HasPrefix(decision.Explain(), decision.Reason) {
+				t.Fatalf("Explain() = %q", decision.Explain())
+			}
+		})
+	}
+
+	allow := newTestPolicyEngine(t, PolicyDocument{Default: PolicyAllow})
+	if decision := allow.Decide(PolicyInput{Action: "anything"}); !decision.Allowed || decision.RuleID != "" {
+		t.Fatalf("default allow = %+v", decision)
+	}
+}
+
+func TestPolicyConditions(t *testing.T) {
+	input := PolicyInput{
+		Caller: &PolicyCaller{ID: "registration", Scopes: []string{"grades.read"}},
+		User:   &PolicyUser{ID: "42", Attributes: map[string]interface{}{"faculty": "FTEIC", "year": 2021}},
+		Method: "PUT",
+		Path:   "/grades/42",
+		Params: map[string]string{"id": "42"},
+		Resource: map[string]interface{}{
+			"owner_id":  float64(42),
+			"faculties": []interface{}{"FTEIC", "FSAD"},
+			"big_id":    float64(1234567890123456),
+			"ratio":     0.5,
+		},
+	}
+
+	tests := []struct {
+		condition PolicyCondition
+		want      bool
+	}{
+		{PolicyCondition{Attr: "params.id", Op: "eq", Ref: "resource.owner_id"}, true},
+		{PolicyCondition{Attr: "params.id", Op: "eq", Value: 42}, true},
+		{PolicyCondition{Attr: "resource.big_id", Op: "eq", Value: "1234567890123456"}, true},
+		{PolicyCondition{Attr: "resource.ratio", Op: "eq", Value: "0.5"}, true},
+		{PolicyCondition{Attr: "user.attributes.year", Op: "eq", Value: float64(2021)}, true},
+		{PolicyCondition{Attr: "params.id", Op: "ne", Value: "43"}, true},
+		{PolicyCondition{Attr: "params.id", Op: "ne", Value: "42"}, false},
+		{PolicyCondition{Attr: "user.attributes.faculty", Op: "in", Ref: "resource.faculties"}, true},
+		{PolicyCondition{Attr: "user.attributes.faculty", Op: "not_in", Value: []interface{}{"FSAD"}}, true},
+		{PolicyCondition{Attr: "caller.scopes", Op: "contains", Value: "grades.read"}, true},
+		{PolicyCondition{Attr: "caller.scopes", Op: "contains", Value: "grades.write"}, false},
+		{PolicyCondition{Attr: "caller.id", Op: "exists"}, true},
+		{PolicyCondition{Attr: "user.attributes.missing", Op: "exists"}, false},
+		{PolicyCondition{Attr: "user.attributes.missing", Op: "eq", Value: "x"}, false},
+		{PolicyCondition{Attr: "params.id", Op: "eq", Ref: "resource.missing"}, false},
+	}
+	for _, tt := range tests {
+		engine := newTestPolicyEngine(t, PolicyDocument{Rules: []PolicyRule{{Effect: PolicyAllow, When: []PolicyCondition{tt.condition}}}})
+		if got := engine.Can(input); got != tt.want {
+			t.Errorf("%s %s %v%s = %v, want %v", tt.condition.Attr, tt.condition.Op, tt.condition.Value, tt.condition.Ref, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyMissingAttributes(t *testing.T) {
+	// Lecturers of other faculties may not grade; the faculty may be unknown.
+	conditions := []PolicyCondition{
+		{Attr: "user.attributes.faculty", Op: "ne", Ref: "resource.faculty"},
+		{Attr: "user.attributes.faculty", Op: "not_in", Value: []interface{}{"FTEIC"}},
+	}
+	inputs := map[string]PolicyInput{
+		"attribute missing": {User: &PolicyUser{ID: "7"}, Resource: map[string]interface{}{"faculty": "FTEIC"}},
+		"ref missing":       {User: &PolicyUser{ID: "7", Attributes: map[string]interface{}{"faculty": "FTEIC"}}},
+		"no user":           {Resource: map[string]interface{}{"faculty": "FTEIC"}},
+	}
+	for _, condition := range conditions {
+		for name, input := range inputs {
+			if condition.Op == "not_in" && name == "ref missing" {
+				continue
+			}
+			deny := newTestPolicyEngine(t, PolicyDocument{Default: PolicyAllow, Rules: []PolicyRule{{ID: "other-faculty", Effect: PolicyDeny, When: []PolicyCondition{condition}}}})
+			if decision := deny.Decide(input); decision.Allowed || decision.RuleID != "other-faculty" {
+				t.Errorf("deny %s, %s: %+v, want the deny rule to match", condition.Op, name, decision)
+			}
+			allow := newTestPolicyEngine(t, PolicyDocument{Rules: []PolicyRule{{ID: "other-faculty", Effect: PolicyAllow, When: []PolicyCondition{condition}}}})
+			if allow.Can(input) {
+				t.Errorf("allow %s, %s: allowed on a missing attribute", condition.Op, name)
+			}
+		}
+	}
+}
+
+func TestNewPolicyEngineValidation(t *testing.T) {
+	for name, document := range map[string]PolicyDocument{
+		"algorithm":  {Algorithm: "majority"},
+		"default":    {Default: "maybe"},
+		"effect":     {Rules: []PolicyRule{{Effect: "permit"}}},
+		"operator":   {Rules: []PolicyRule{{Effect: PolicyAllow, When: []PolicyCondition{{Attr: "user.id", Op: "gt"}}}}},
+		"attr":       {Rules: []PolicyRule{{Effect: PolicyAllow, When: []PolicyCondition{{Op: "exists"}}}}},
+		"duplicates": {Rules: []PolicyRule{{ID: "a", Effect: PolicyAllow}, {ID: "a", Effect: PolicyDeny}}},
+	} {
+		if _, err := NewPolicyEngine(document); err == nil {
+			t.Errorf("%s: invalid document accepted", name)
+		}
+	}
+}
+
+func TestLoadPolicyEngine(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"10-base.yaml": "algorithm: first_match\nrules:\n  - id: admins\n    effect: allow\n    when:\n      - {attr: user.roles, op: contains, value: admin}\n",
+		"20-more.json": `{"algorithm": "deny_overrides", "default": "allow", "rules": [{"id": "no-delete", "effect": "deny", "methods": ["DELETE"]}]}`,
+		"notes.txt":    "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	engine, err := LoadPolicyEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := &PolicyUser{Roles: []string{"admin"}}
+	// first_match from the first file: the admin rule, read first, wins over no-delete.
+	if decision := engine.Decide(PolicyInput{Method: "DELETE", User: admin}); !decision.Allowed || decision.RuleID != "admins" {
+		t.Fatalf("admin delete = %+v", decision)
+	}
+	if decision := engine.Decide(PolicyInput{Method: "DELETE", User: &PolicyUser{}}); decision.Allowed || decision.RuleID != "no-delete" {
+		t.Fatalf("user delete = %+v", decision)
+	}
+	// The default comes from the second file, the first leaving it unset.
+	if !engine.Can(PolicyInput{Method: "GET", User: &PolicyUser{}}) {
+		t.Fatal("default allow from the second file not applied")
+	}
+
+	broken := filepath.Join(t.TempDir(), "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"rules": [`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicyEngine(broken); err == nil {
+		t.Fatal("LoadPolicyEngine() accepted invalid JSON")
+	}
+	duplicate := filepath.Join(t.TempDir(), "admins.yaml")
+	if err := os.WriteFile(duplicate, []byte(files["10-base.yaml"]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPolicyEngine(dir, duplicate); err == nil {
+		t.Fatal("LoadPolicyEngine() accepted a rule id defined twice")
+	}
+	if _, err := LoadPolicyEngine(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Fatal("LoadPolicyEngine() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,349 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisError is an error reply ("-ERR ...") sent by the server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// ErrRedisNil is returned by the typed helpers when the reply is a nil bulk string.
+var ErrRedisNil = errors.New("redis: nil reply")
+
+// RedisClient is a minimal RESP2 client for the few commands this package needs
+// (queues, nonces, locks). It speaks to Redis or any protocol compatible stand-in.
+type RedisClient struct {
+	Addr     string
+	Password string
+	DB       int
+	Timeout  time.Duration
+
+	mu   sync.Mutex
+	idle []*redisConn
+	// blocking holds the connections of blocking commands, true while in use.
+	// They are reused between commands and closed by Close.
+	blocking map[*redisConn]bool
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisClient creates a client for addr ("host:port").
+func NewRedisClient(addr string) *RedisClient {
+	return &RedisClient{Addr: addr, Timeout: 5 * time.Second}
+}
+
+// NewRedisClientFromEnv uses the Laravel variables REDIS_HOST, REDIS_PORT,
+// REDIS_PASSWORD and REDIS_DB.
+func NewRedisClientFromEnv() *RedisClient {
+	client := NewRedisClient(net.JoinHostPort(GetEnv("REDIS_HOST", "127.0.0.1"), GetEnv("REDIS_PORT", "6379")))
+	client.Password = GetEnv("REDIS_PASSWORD", "")
+	if client.Password == "null" {
+		client.Password = ""
+	}
+	client.DB, _ = strconv.Atoi(GetEnv("REDIS_DB", "0"))
+	return client
+}
+
+// Do sends one command and returns its reply: string for simple and bulk strings,
+// int64 for integers, []interface{} for arrays and nil for nil replies.
+// Error replies are returned as RedisError.
+func (r *RedisClient) Do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, r.Timeout, args)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		conn.conn.Close()
+		return nil, err
+	}
+	r.put(conn)
+	return reply, err
+}
+
+// Blocking sends a command that may block server side (BRPOP, BLPOP) on a
+// connection reserved for blocking commands, waiting at most until ctx is done.
+// The connection is reused by the next blocking command unless ctx ended or
+// the connection failed; Close interrupts the command.
+func (r *RedisClient) Blocking(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.getBlocking(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancellation is left to AfterFunc alone: a socket deadline taken from ctx
+	// could fire before ctx reports done and surface as a plain i/o timeout.
+	conn.conn.SetDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() { conn.conn.SetDeadline(time.Now()) })
+	reply, err := conn.roundTrip(args)
+	interrupted := !stop()
+
+	var redisErr RedisError
+	if interrupted || (err != nil && !errors.As(err, &redisErr)) {
+		r.dropBlocking(conn)
+	} else {
+		r.putBlocking(conn)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return reply, err
+}
+
+// String runs a command whose reply is a string.
+func (r *RedisClient) String(ctx context.Context, args ...string) (string, error) {
+	reply, err := r.Do(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	switch reply := reply.(type) {
+	case nil:
+		return "", ErrRedisNil
+	case string:
+		return reply, nil
+	case int64:
+		return strconv.FormatInt(reply, 10), nil
+	}
+	return "", fmt.Errorf("redis: unexpected reply %T", reply)
+}
+
+// Int runs a command whose reply is an integer.
+func (r *RedisClient) Int(ctx context.Context, args ...string) (int64, error) {
+	reply, err := r.Do(ctx, args...)
+	if err != nil {
+		return 0, err
+	}
+	switch reply := reply.(type) {
+	case int64:
+		return reply, nil
+	case string:
+		return strconv.ParseInt(reply, 10, 64)
+	case nil:
+		return 0, ErrRedisNil
+	}
+	return 0, fmt.Errorf("redis: unexpected reply %T", reply)
+}
+
+// Close closes the idle connections and those of blocking commands, which
+// interrupts running ones. The client stays usable and dials again when needed.
+func (r *RedisClient) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, conn := range r.idle {
+		conn.conn.Close()
+	}
+	r.idle = nil
+	for conn := range r.blocking {
+		conn.conn.Close()
+	}
+	r.blocking = nil
+	return nil
+}
+
+func (r *RedisClient) get(ctx context.Context) (*redisConn, error) {
+	r.mu.Lock()
+	if n := len(r.idle); n > 0 {
+		conn := r.idle[n-1]
+		r.idle = r.idle[:n-1]
+		r.mu.Unlock()
+		return conn, nil
+	}
+	r.mu.Unlock()
+	return r.dial(ctx)
+}
+
+func (r *RedisClient) put(conn *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.idle) >= 8 {
+		conn.conn.Close()
+		return
+	}
+	r.idle = append(r.idle, conn)
+}
+
+func (r *RedisClient) getBlocking(ctx context.Context) (*redisConn, error) {
+	r.mu.Lock()
+	for conn, inUse := range r.blocking {
+		if !inUse {
+			r.blocking[conn] = true
+			r.mu.Unlock()
+			return conn, nil
+		}
+	}
+	r.mu.Unlock()
+
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.blocking == nil {
+		r.blocking = map[*redisConn]bool{}
+	}
+	r.blocking[conn] = true
+	return conn, nil
+}
+
+// putBlocking makes conn available to the next blocking command, unless Close
+// dropped it meanwhile.
+func (r *RedisClient) putBlocking(conn *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.blocking[conn]; ok {
+		r.blocking[conn] = false
+		return
+	}
+	conn.conn.Close()
+}
+
+func (r *RedisClient) dropBlocking(conn *redisConn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.blocking, conn)
+	conn.conn.Close()
+}
+
+func (r *RedisClient) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: r.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if r.Password != "" {
+		if _, err := conn.do(ctx, r.Timeout, []string{"AUTH", r.Password}); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if r.DB != 0 {
+		if _, err := conn.do(ctx, r.Timeout, []string{"SELECT", strconv.Itoa(r.DB)}); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *redisConn) do(ctx context.Context, timeout time.Duration, args []string) (interface{}, error) {
+	deadline := time.Time{}
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+	return c.roundTrip(args)
+}
+
+// roundTrip writes one command and reads its reply.
+func (c *redisConn) roundTrip(args []string) (interface{}, error) {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRESP(c.reader)
+}
+
+// Limits on the sizes announced by the server. Bulk strings follow Redis's own
+// proto-max-bulk-len; memory is only allocated as the data arrives.
+const (
+	redisMaxBulkSize  = 512 << 20
+	redisMaxArraySize = 1 << 20
+)
+
+func readRESP(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply")
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, RedisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		if size > redisMaxBulkSize {
+			return nil, fmt.Errorf("redis: bulk string of %d bytes exceeds the limit", size)
+		}
+		var data bytes.Buffer
+		if _, err := io.CopyN(&data, reader, int64(size)+2); err != nil {
+			return nil, err
+		}
+		if !bytes.HasSuffix(data.Bytes(), []byte("\r\n")) {
+			return nil, errors.New("redis: malformed reply")
+		}
+		return string(data.Bytes()[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		if count > redisMaxArraySize {
+			return nil, fmt.Errorf("redis: array of %d items exceeds the limit", count)
+		}
+		items := make([]interface{}, 0, min(count, 64))
+		for i := 0; i < count; i++ {
+			item, err := readRESP(reader)
+			if err != nil {
+				var redisErr RedisError
+				if !errors.As(err, &redisErr) {
+					return nil, err
+				}
+				item = redisErr
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
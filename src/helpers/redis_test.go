@@ -0,0 +1,150 @@
+package helpers
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SIM-MBKM/mod-service/src/redistest"
+)
+
+func newTestRedis(t *testing.T) (*redistest.Server, *RedisClient) {
+	t.Helper()
+	server := redistest.NewServer()
+	t.Cleanup(server.Close)
+	client := NewRedisClient(server.Addr)
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func countCommands(server *redistest.Server, name string) int {
+	count := 0
+	for _, command := range server.Commands() {
+		if strings.EqualFold(command[0], name) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRedisClientBlockingReusesConnection(t *testing.T) {
+	server, client := newTestRedis(t)
+	server.RequirePass("secret")
+	client.Password, client.DB = "secret", 2
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.Do(ctx, "LPUSH", "queue", "job"); err != nil {
+			t.Fatal(err)
+		}
+		reply, err := client.Blocking(ctx, "BRPOP", "queue", "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if items, ok := reply.([]interface{}); !ok || len(items) != 2 || items[1] != "job" {
+			t.Fatalf("BRPOP reply = %#v", reply)
+		}
+	}
+	// One pooled connection for LPUSH, one reserved for BRPOP.
+	if got := server.Connections(); got != 2 {
+		t.Fatalf("%d connections, want 2", got)
+	}
+	if got := countCommands(server, "AUTH"); got != 2 {
+		t.Fatalf("AUTH sent %d times, want 2", got)
+	}
+	if got := countCommands(server, "SELECT"); got != 2 {
+		t.Fatalf("SELECT sent %d times, want 2", got)
+	}
+}
+
+func TestRedisClientBlockingCancel(t *testing.T) {
+	server, client := newTestRedis(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Blocking(ctx, "BRPOP", "queue", "0"); err != context.DeadlineExceeded {
+		t.Fatalf("Blocking() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The interrupted connection may still have a BRPOP pending; it is not reused.
+	time.Sleep(50 * time.Millisecond)
+	server.Push("queue", "job")
+	reply, err := client.Blocking(context.Background(), "BRPOP", "queue", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items, ok := reply.([]interface{}); !ok || len(items) != 2 || items[1] != "job" {
+		t.Fatalf("BRPOP reply = %#v, want the pushed job", reply)
+	}
+	if got := server.Connections(); got != 2 {
+		t.Fatalf("%d connections, want 2", got)
+	}
+}
+
+func TestRedisClientCloseInterruptsBlocking(t *testing.T) {
+	_, client := newTestRedis(t)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Blocking(context.Background(), "BRPOP", "queue", "0")
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	client.Close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Blocking() after Close returned no error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not interrupt Blocking")
+	}
+}
+
+func TestReadRESPLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    interface{}
+		wantErr bool
+	}{
+		{"bulk", "$5\r\nhello\r\n", "hello", false},
+		{"nil bulk", "$-1\r\n", nil, false},
+		{"huge bulk", "$1000000000\r\n", nil, true},
+		{"short bulk", "$100\r\nhello\r\n", nil, true},
+		{"unterminated bulk", "$5\r\nhello!!", nil, true},
+		{"huge array", "*100000000\r\n", nil, true},
+		{"array", "*2\r\n:1\r\n+OK\r\n", []interface{}{int64(1), "OK"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readRESP(bufio.NewReader(strings.NewReader(tt.reply)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readRESP() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !equalRESP(got, tt.want) {
+				t.Fatalf("readRESP() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalRESP(a, b interface{}) bool {
+	as, aok := a.([]interface{})
+	bs, bok := b.([]interface{})
+	if !aok || !bok {
+		return a == b
+	}
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if !equalRESP(as[i], bs[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package helpers
+
+import "testing"
+
+func TestRequestSignerVerify(t *testing.T) {
+	signer := NewRequestSigner("app-key")
+	body := []byte(`{"nim":"5025201001"}`)
+	const timestamp = 1700000000
+	signature := signer.Sign("POST", "/students/1", "b=2&a=1", body, timestamp)
+
+	tests := []struct {
+		name      string
+		signer    *RequestSigner
+		method    string
+		path      string
+		query     string
+		body      []byte
+		timestamp int64
+		want      bool
+	}{
+		{"same request", signer, "POST", "/students/1", "b=2&a=1", body, timestamp, true},
+		{"method case", signer, "post", "/students/1", "b=2&a=1", body, timestamp, true},
+		{"query reordered", signer, "POST", "/students/1", "a=1&b=2", body, timestamp, true},
+		{"query re-escaped", signer, "POST", "/students/1", "a=%31&b=2", body, timestamp, true},
+		{"other method", signer, "PUT", "/students/1", "b=2&a=1", body, timestamp, false},
+		{"other path", signer, "POST", "/students/2", "b=2&a=1", body, timestamp, false},
+		{"other query", signer, "POST", "/students/1", "b=3&a=1", body, timestamp, false},
+		{"extra query", signer, "POST", "/students/1", "b=2&a=1&c=3", body, timestamp, false},
+		{"other body", signer, "POST", "/students/1", "b=2&a=1", []byte(`{"nim":"5025201002"}`), timestamp, false},
+		{"no body", signer, "POST", "/students/1", "b=2&a=1", nil, timestamp, false},
+		{"other timestamp", signer, "POST", "/students/1", "b=2&a=1", body, timestamp + 1, false},
+		{"other key", NewRequestSigner("other-key"), "POST", "/students/1", "b=2&a=1", body, timestamp, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signer.Verify(signature, tt.method, tt.path, tt.query, tt.body, tt.timestamp); got != tt.want {
+				t.Fatalf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalRequest(t *testing.T) {
+	got := CanonicalRequest("get", "", "z=1&a=2&a=1", nil, 1700000000)
+	want := "v1\nGET\n/\na=1&a=2&z=1\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n1700000000"
+	if got != want {
+		t.Fatalf("CanonicalRequest() = %q, want %q", got, want)
+	}
+}
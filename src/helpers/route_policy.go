@@ -0,0 +1,268 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRoutePolicyReload is how often a policy file is checked for changes.
+const DefaultRoutePolicyReload = 10 * time.Second
+
+// RouteRule grants callers, or callers holding scopes, access to a route.
+type RouteRule struct {
+	Name string `json:"name" yaml:"name"`
+	// Path is a pattern such as "/internal/grades/:id". ":name" and "*" match one
+	// segment; a final "**" or "*name" matches the rest of the path.
+	Path string `json:"path" yaml:"path"`
+	// Methods limits the rule to these HTTP methods; empty or "*" means any.
+	Methods []string `json:"methods" yaml:"methods"`
+	// Callers are caller IDs allowed on the route; "*" allows every caller.
+	Callers []string `json:"callers" yaml:"callers"`
+	// Scopes, all of which a caller must hold when not listed in Callers.
+	Scopes []string `json:"scopes" yaml:"scopes"`
+}
+
+// RoutePolicyDocument is the content of a route policy file:
+//
+//	default: deny
+//	dry_run: false
+//	rules:
+//	  - path: /internal/grades
+//	    methods: [POST]
+//	    callers: [registration]
+//	    scopes: [grades.write]
+type RoutePolicyDocument struct {
+	// Default is "allow" or "deny" (the default) for routes no rule matches.
+	Default string `json:"default" yaml:"default"`
+	// DryRun logs denials without enforcing them.
+	DryRun bool        `json:"dry_run" yaml:"dry_run"`
+	Rules  []RouteRule `json:"rules" yaml:"rules"`
+}
+
+// RouteDecision is the outcome of RoutePolicy.Evaluate.
+type RouteDecision struct {
+	Allowed bool
+	// Rule is the rule that decided, nil when the default applied.
+	Rule *RouteRule
+	// RequiredScopes are the scopes that would have granted access.
+	RequiredScopes []string
+	// Reason explains a denial.
+	Reason string
+	// DryRun is true when the denial must only be logged.
+	DryRun bool
+}
+
+// RoutePolicy is an authorization matrix of which calling service may reach
+// which routes. Policies loaded from a file reload themselves when it changes.
+type RoutePolicy struct {
+	path           string
+	reloadInterval time.Duration
+
+	mu        sync.Mutex
+	document  RoutePolicyDocument
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewRoutePolicy creates a policy from document.
+func NewRoutePolicy(document RoutePolicyDocument) (*RoutePolicy, error) {
+	if err := validateRoutePolicy(&document); err != nil {
+		return nil, err
+	}
+	return &RoutePolicy{document: document}, nil
+}
+
+// LoadRoutePolicy reads a JSON or YAML policy file and reloads it when its
+// modification time changes, checking at most every reloadInterval (zero uses
+// DefaultRoutePolicyReload). An invalid new version is logged and the previous
+// one stays in force.
+func LoadRoutePolicy(path string, reloadInterval time.Duration) (*RoutePolicy, error) {
+	if reloadInterval <= 0 {
+		reloadInterval = DefaultRoutePolicyReload
+	}
+	policy := &RoutePolicy{path: path, reloadInterval: reloadInterval}
+	if err := policy.Reload(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Reload reads the policy file again.
+func (p *RoutePolicy) Reload() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.load(time.Now())
+}
+
+func (p *RoutePolicy) load(now time.Time) error {
+	p.checkedAt = now
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	var document RoutePolicyDocument
+	switch strings.ToLower(filepath.Ext(p.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	default:
+		err = json.Unmarshal(data, &document)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid route policy %s: %v", p.path, err)
+	}
+	if err := validateRoutePolicy(&document); err != nil {
+		return fmt.Errorf("invalid route policy %s: %v", p.path, err)
+	}
+
+	p.document = document
+	p.modTime = info.ModTime()
+	return nil
+}
+
+// current returns the document in force, reloading a changed file first.
+func (p *RoutePolicy) current() RoutePolicyDocument {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.path != "" && now.Sub(p.checkedAt) >= p.reloadInterval {
+		p.checkedAt = now
+		if info, err := os.Stat(p.path); err != nil {
+			log.Printf("route policy: %v", err)
+		} else if !info.ModTime().Equal(p.modTime) {
+			if err := p.load(now); err != nil {
+				// Do not retry the same broken version on every check.
+				p.modTime = info.ModTime()
+				log.Printf("route policy: keeping previous version: %v", err)
+			}
+		}
+	}
+	return p.document
+}
+
+func validateRoutePolicy(document *RoutePolicyDocument) error {
+	switch strings.ToLower(document.Default) {
+	case "":
+		document.Default = "deny"
+	case "allow", "deny":
+		document.Default = strings.ToLower(document.Default)
+	default:
+		return fmt.Errorf("default must be allow or deny, got %q", document.Default)
+	}
+	for i, rule := range document.Rules {
+		if rule.Path == "" || !strings.HasPrefix(rule.Path, "/") {
+			return fmt.Errorf("rule %d: path must start with /", i+1)
+		}
+		if len(rule.Callers) == 0 && len(rule.Scopes) == 0 {
+			return fmt.Errorf("rule %d (%s): needs callers or scopes", i+1, rule.Path)
+		}
+	}
+	return nil
+}
+
+// Evaluate decides whether callerID, holding scopes, may call method path.
+// The first rule matching the method and path decides; routes no rule matches
+// get the policy default.
+func (p *RoutePolicy) Evaluate(callerID string, scopes []string, method, path string) RouteDecision {
+	document := p.current()
+
+	for i := range document.Rules {
+		rule := &document.Rules[i]
+		if !rule.matchesMethod(method) || !MatchRoutePattern(rule.Path, path) {
+			continue
+		}
+
+		decision := RouteDecision{Rule: rule, RequiredScopes: rule.Scopes, DryRun: document.DryRun}
+		if containsValue(rule.Callers, "*") || (callerID != "" && containsValue(rule.Callers, callerID)) {
+			decision.Allowed = true
+			return decision
+		}
+		if len(rule.Scopes) > 0 && hasAllScopes(scopes, rule.Scopes) {
+			decision.Allowed = true
+			return decision
+		}
+
+		if len(rule.Scopes) > 0 {
+			decision.Reason = fmt.Sprintf("%s %s requires scope %s", method, path, strings.Join(rule.Scopes, ", "))
+		} else {
+			decision.Reason = fmt.Sprintf("caller %q may not call %s %s", callerID, method, path)
+		}
+		return decision
+	}
+
+	if document.Default == "allow" {
+		return RouteDecision{Allowed: true}
+	}
+	return RouteDecision{
+		Reason: fmt.Sprintf("no route policy rule allows %s %s", method, path),
+		DryRun: document.DryRun,
+	}
+}
+
+func (r *RouteRule) matchesMethod(method string) bool {
+	return len(r.Methods) == 0 || containsFold(r.Methods, "*") || containsFold(r.Methods, method)
+}
+
+// MatchRoutePattern reports whether path matches a RouteRule pattern.
+func MatchRoutePattern(pattern, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range patternSegments {
+		if i == len(patternSegments)-1 && (segment == "**" || (strings.HasPrefix(segment, "*") && len(segment) > 1)) {
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		switch {
+		case segment == "*", strings.HasPrefix(segment, ":"):
+			if pathSegments[i] == "" {
+				return false
+			}
+		case segment != pathSegments[i]:
+			return false
+		}
+	}
+	return len(pathSegments) == len(patternSegments)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsValue(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAllScopes(granted, required []string) bool {
+	for _, scope := range required {
+		if !containsValue(granted, scope) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatchRoutePattern(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/internal/grades", "/internal/grades", true},
+		{"/internal/grades", "/internal/grades/", true},
+		{"/internal/grades/", "/internal/grades", true},
+		{"/internal/grades", "/internal/grade", false},
+		{"/internal/grades", "/internal/grades/1", false},
+		{"/internal/grades/:id", "/internal/grades/42", true},
+		{"/internal/grades/:id", "/internal/grades", false},
+		{"/internal/grades/:id", "/internal/grades/42/history", false},
+		{"/internal/*/history", "/internal/grades/history", true},
+		{"/internal/*/history", "/internal//history", false},
+		{"/internal/**", "/internal/grades/42/history", true},
+		{"/internal/**", "/internal", true},
+		{"/internal/**", "/public/grades", false},
+		{"/internal/*rest", "/internal/grades/42", true},
+		{"/", "/", true},
+		{"/", "/grades", false},
+	}
+	for _, tt := range tests {
+		if got := MatchRoutePattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchRoutePattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRoutePolicyEvaluate(t *testing.T) {
+	policy, err := NewRoutePolicy(RoutePolicyDocument{Rules: []RouteRule{
+		{Name: "grades read", Path: "/internal/grades/:id", Methods: []string{"get"}, Callers: []string{"*"}},
+		{Name: "grades write", Path: "/internal/grades/:id", Callers: []string{"registration"}, Scopes: []string{"grades.write", "grades.read"}},
+		// Never reached for grades: the rules above match first.
+		{Name: "internal", Path: "/internal/**", Callers: []string{"admin"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		caller   string
+		scopes   []string
+		method   string
+		path     string
+		allowed  bool
+		rule     string
+		required []string
+	}{
+		{"wildcard caller", "grades", nil, "GET", "/internal/grades/1", true, "grades read", nil},
+		{"wildcard caller without id", "", nil, "GET", "/internal/grades/1", true, "grades read", nil},
+		{"listed caller", "registration", nil, "POST", "/internal/grades/1", true, "grades write", []string{"grades.write", "grades.read"}},
+		{"all scopes", "grades", []string{"grades.read", "grades.write"}, "PUT", "/internal/grades/1", true, "grades write", []string{"grades.write", "grades.read"}},
+		{"missing scope", "grades", []string{"grades.write"}, "PUT", "/internal/grades/1", false, "grades write", []string{"grades.write", "grades.read"}},
+		// The first matching rule decides, even though a later one would allow admin.
+		{"first match wins", "admin", nil, "DELETE", "/internal/grades/1", false, "grades write", []string{"grades.write", "grades.read"}},
+		{"later rule", "admin", nil, "GET", "/internal/students", true, "internal", nil},
+		{"later rule denies", "grades", nil, "GET", "/internal/students", false, "internal", nil},
+		{"default deny", "admin", nil, "GET", "/public", false, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := policy.Evaluate(tt.caller, tt.scopes, tt.method, tt.path)
+			if decision.Allowed != tt.allowed || (decision.Reason == "") != tt.allowed {
+				t.Fatalf("decision = %+v, want allowed %v", decision, tt.allowed)
+			}
+			rule := ""
+			if decision.Rule != nil {
+				rule = decision.Rule.Name
+			}
+			if rule != tt.rule || !reflect.DeepEqual(decision.RequiredScopes, tt.required) {
+				t.Fatalf("rule %q, required %v, want %q, %v", rule, decision.RequiredScopes, tt.rule, tt.required)
+			}
+		})
+	}
+}
+
+func TestRoutePolicyDefaultAndDryRun(t *testing.T) {
+	allow, err := NewRoutePolicy(RoutePolicyDocument{Default: "ALLOW"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision := allow.Evaluate("", nil, "GET", "/anything"); !decision.Allowed {
+		t.Fatalf("default allow = %+v", decision)
+	}
+
+	dryRun, err := NewRoutePolicy(RoutePolicyDocument{DryRun: true, Rules: []RouteRule{{Path: "/internal", Callers: []string{"admin"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/internal", "/public"} {
+		if decision := dryRun.Evaluate("grades", nil, "GET", path); decision.Allowed || !decision.DryRun {
+			t.Fatalf("dry run %s = %+v, want a dry run denial", path, decision)
+		}
+	}
+
+	for _, document := range []RoutePolicyDocument{
+		{Default: "maybe"},
+		{Rules: []RouteRule{{Path: "internal", Callers: []string{"admin"}}}},
+		{Rules: []RouteRule{{Path: "/internal"}}},
+	} {
+		if _, err := NewRoutePolicy(document); err == nil {
+			t.Fatalf("NewRoutePolicy(%+v) accepted an invalid document", document)
+		}
+	}
+}
+
+func TestLoadRoutePolicyReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	modTime := time.Now().Add(-time.Hour)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// Distinct modification times, however coarse the filesystem clock.
+		modTime = modTime.Add(time.Second)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	allowed := func(policy *RoutePolicy, caller string) bool {
+		time.Sleep(5 * time.Millisecond)
+		return policy.Evaluate(caller, nil, "GET", "/internal/grades").Allowed
+	}
+
+	write("rules:\n  - path: /internal/grades\n    callers: [registration]\n")
+	policy, err := LoadRoutePolicy(path, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !allowed(policy, "registration") || allowed(policy, "grades") {
+		t.Fatal("initial policy not applied")
+	}
+
+	write("rules:\n  - path: /internal/grades\n    callers: [grades]\n")
+	if !allowed(policy, "grades") || allowed(policy, "registration") {
+		t.Fatal("changed policy not reloaded")
+	}
+
+	// An invalid version keeps the previous one in force.
+	write("default: sometimes\nrules: [")
+	if !allowed(policy, "grades") || allowed(policy, "registration") {
+		t.Fatal("invalid policy replaced the previous version")
+	}
+	write("rules:\n  - path: internal\n    callers: [registration]\n")
+	if !allowed(policy, "grades") {
+		t.Fatal("policy failing validation replaced the previous version")
+	}
+
+	if _, err := LoadRoutePolicy(filepath.Join(t.TempDir(), "missing.json"), 0); err == nil {
+		t.Fatal("LoadRoutePolicy() of a missing file succeeded")
+	}
+}
@@ -3,7 +3,7 @@ package helpers
 import (
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -17,7 +17,10 @@ type Security struct {
 	cipherMode string
 }
 
-// NewSecurity creates a new instance of Security with configuration
+// NewSecurity creates a new instance of Security with configuration. Empty names
+// mean sha256 and aes-256-cbc. The configuration is not checked here, unknown
+// names fail every Encrypt and Decrypt; use NewSecurityWithConfig to reject them
+// up front.
 func NewSecurity(hashMethod, key, cipherMode string) *Security {
 	return &Security{
 		hashMethod: hashMethod,
@@ -26,39 +29,81 @@ func NewSecurity(hashMethod, key, cipherMode string) *Security {
 	}
 }
 
+// NewSecurityWithConfig creates a Security after checking that hashMethod and
+// cipherMode are registered and that key has the size the cipher needs.
+func NewSecurityWithConfig(hashMethod, key, cipherMode string) (*Security, error) {
+	if _, err := LookupHash(hashMethod); err != nil {
+		return nil, err
+	}
+	if _, err := LookupCipher(cipherMode); err != nil {
+		return nil, err
+	}
+	s := NewSecurity(hashMethod, key, cipherMode)
+	if _, err := s.decodedKey(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
 // hash generates a hash based on the hash method
-func (s *Security) hash(value string) []byte {
-	hash := sha256.Sum256([]byte(value)) // Defaulting to SHA-256
-	return hash[:]
+func (s *Security) hash(value string) ([]byte, error) {
+	fn, err := resolveHash(s.hashMethod)
+	if err != nil {
+		return nil, err
+	}
+	hasher := fn()
+	hasher.Write([]byte(value))
+	return hasher.Sum(nil), nil
 }
 
-// Encrypt encrypts the given value
-func (s *Security) Encrypt(value interface{}) (string, error) {
-	// Serializing value to string (equivalent to PHP serialize)
-	serializedValue, err := json.Marshal(value)
+// cipherSpec returns the configured cipher, aes-256-cbc when unset.
+func (s *Security) cipherSpec() (CipherSpec, error) {
+	return resolveCipher(s.cipherMode)
+}
+
+// EncryptString implements Encrypter.
+func (s *Security) EncryptString(value string) (string, error) {
+	return s.Encrypt(value)
+}
+
+// DecryptString implements Encrypter.
+func (s *Security) DecryptString(value string) (string, error) {
+	decrypted, err := s.Decrypt(value)
 	if err != nil {
 		return "", err
 	}
+	str, ok := decrypted.(string)
+	if !ok {
+		return "", fmt.Errorf("decrypted value is %T, not a string", decrypted)
+	}
+	return str, nil
+}
 
-	// Cek jika key diawali dengan "base64:" dan hapus awalan tersebut
-	key := s.key
-	if len(key) > 7 && key[:7] == "base64:" {
-		key = key[7:]
+// Encrypt encrypts the given value. With an AEAD cipher mode (aes-128-gcm,
+// aes-256-gcm or chacha20-poly1305) it produces the versioned "v2:" format with a random nonce,
+// otherwise the legacy AES-CBC format.
+func (s *Security) Encrypt(value interface{}) (string, error) {
+	if isAEADMode(s.cipherMode) {
+		return s.encryptAEAD(value)
 	}
 
-	// Decode base64 key
-	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	// Serializing value to string (equivalent to PHP serialize)
+	serializedValue, err := json.Marshal(value)
 	if err != nil {
-		return "", fmt.Errorf("error decoding key: %v", err)
+		return "", err
 	}
 
-	// Pastikan panjang decoded key sesuai untuk AES
-	if len(decodedKey) != 32 {
-		return "", fmt.Errorf("invalid key length, expected 32 bytes, got %d bytes", len(decodedKey))
+	// Decode base64 key (awalan "base64:" diabaikan) dan pastikan panjangnya sesuai untuk AES
+	decodedKey, err := s.decodedKey()
+	if err != nil {
+		return "", err
 	}
 
 	// Generate hash untuk key
-	keyHash := s.hash(string(decodedKey))
+	keyHash, err := s.hash(string(decodedKey))
+	if err != nil {
+		return "", err
+	}
 	// Gunakan bytes pertama AES.BlockSize sebagai IV
 	iv := keyHash[:aes.BlockSize]
 
@@ -82,51 +127,68 @@ func (s *Security) Encrypt(value interface{}) (string, error) {
 	return base64.StdEncoding.EncodeToString(encrypted), nil
 }
 
-// Decrypt decrypts the given encrypted value using AES-256-CBC
+// Decrypt decrypts the given encrypted value. The format is detected from the
+// value, so legacy AES-CBC values keep decrypting after switching to AEAD.
+// Malformed input never panics; it yields an error wrapping ErrDecryptBase64,
+// ErrDecryptLength, ErrDecryptPadding, ErrDecryptMAC or ErrDecryptSerialization.
 func (s *Security) Decrypt(encryptedValue string) (interface{}, error) {
+	if strings.HasPrefix(encryptedValue, aeadPrefix) {
+		return s.decryptAEAD(encryptedValue)
+	}
+
 	// Decode the base64 encoded encrypted value
 	data, err := base64.StdEncoding.DecodeString(encryptedValue)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrDecryptBase64, err)
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes is not a multiple of the block size", ErrDecryptLength, len(data))
 	}
 
-	// Decode base64 key
-	key, err := base64.StdEncoding.DecodeString(s.key)
+	// Decode base64 key (awalan "base64:" diabaikan)
+	key, err := s.decodedKey()
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate hash for key
-	keyHash := s.hash(string(key))
-
-	// Use first AES.BlockSize bytes as IV
+	// Generate hash for key, use first AES.BlockSize bytes as IV
+	keyHash, err := s.hash(string(key))
+	if err != nil {
+		return nil, err
+	}
 	iv := keyHash[:aes.BlockSize]
 
-	// Initialize AES cipher block
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
-	// Initialize the AES CBC decrypter
-	mode := cipher.NewCBCDecrypter(block, iv)
-
-	// Decrypt the data
 	decrypted := make([]byte, len(data))
-	mode.CryptBlocks(decrypted, data)
-
-	// Remove padding (check for null characters and remove them if necessary)
-	padding := decrypted[len(decrypted)-1]
-	decrypted = decrypted[:len(decrypted)-int(padding)]
-
-	// Trim any remaining null characters
-	decrypted = []byte(strings.TrimRight(string(decrypted), "\x00"))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, data)
 
-	// Unmarshal the decrypted data
-	var result interface{}
-	err = json.Unmarshal([]byte(decrypted), &result)
+	unpadded, err := zeroUnpad(decrypted, aes.BlockSize)
 	if err != nil {
 		return nil, err
 	}
 
+	// Unmarshal the decrypted data
+	var result interface{}
+	if err := json.Unmarshal(unpadded, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptSerialization, err)
+	}
 	return result, nil
 }
+
+// zeroUnpad removes the 1 to blockSize NUL bytes Encrypt appends. Values ending
+// in a non-zero byte are treated as PKCS#7, which older decryption accepted too.
+func zeroUnpad(data []byte, blockSize int) ([]byte, error) {
+	length := len(data)
+	if data[length-1] != 0 {
+		return pkcs7Unpad(data, blockSize)
+	}
+	trailing, zero := 0, 1
+	for i := 1; i <= blockSize; i++ {
+		zero &= subtle.ConstantTimeByteEq(data[length-i], 0)
+		trailing += zero
+	}
+	return data[:length-trailing], nil
+}
@@ -4,22 +4,34 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 )
 
+var (
+	// ErrAccessKeyInvalid means the Access-Key cannot be decrypted or does not carry the expected secret.
+	ErrAccessKeyInvalid = errors.New("access key: invalid")
+	// ErrAccessKeyExpired means the Access-Key timestamp is in the future or older than the validity window.
+	ErrAccessKeyExpired = errors.New("access key: expired")
+)
+
 // SecurityAccessKey implementasi yang kompatibel dengan Laravel Security
-type SecurityAccessKey struct{}
+type SecurityAccessKey struct {
+	key    string
+	hash   string
+	cipher string
+}
 
-// GetKey mendapatkan APP_KEY dari environment
+// GetKey mendapatkan APP_KEY dari environment, kecuali key diberikan lewat NewSecurityAccessKeyWithKey
 func (w *SecurityAccessKey) GetKey() string {
+	if w.key != "" {
+		return w.key
+	}
 	key := os.Getenv("APP_KEY")
 	if key == "" {
 		key = "v9N+xLCNqMhbBWv1YNFLDpFDR9S1e62gHHfdIwYQHYs="
@@ -29,6 +41,9 @@ func (w *SecurityAccessKey) GetKey() string {
 
 // GetHash mendapatkan hash method
 func (w *SecurityAccessKey) GetHash() string {
+	if w.hash != "" {
+		return w.hash
+	}
 	hash := os.Getenv("APP_HASH")
 	if hash == "" {
 		hash = "sha256"
@@ -38,6 +53,9 @@ func (w *SecurityAccessKey) GetHash() string {
 
 // GetCipher mendapatkan cipher
 func (w *SecurityAccessKey) GetCipher() string {
+	if w.cipher != "" {
+		return w.cipher
+	}
 	cipher := os.Getenv("APP_CIPHER")
 	if cipher == "" {
 		cipher = "aes-256-cbc"
@@ -45,8 +63,13 @@ func (w *SecurityAccessKey) GetCipher() string {
 	return cipher
 }
 
-// GenerateAccessKey membuat Access-Key yang kompatibel dengan Laravel
+// GenerateAccessKey membuat Access-Key yang kompatibel dengan Laravel, atau
+// Access-Key v2 tanpa audience jika ACCESS_KEY_VERSION=2.
 func (w *SecurityAccessKey) GenerateAccessKey() (string, error) {
+	if AccessKeyVersion() == AccessKeyV2 {
+		return w.GenerateAccessKeyV2("")
+	}
+
 	// Format nilai: key@timestamp
 	timestamp := time.Now().Unix()
 	value := fmt.Sprintf("%s@%d", w.GetKey(), timestamp)
@@ -55,10 +78,67 @@ func (w *SecurityAccessKey) GenerateAccessKey() (string, error) {
 	return w.Encrypt(value)
 }
 
+// GenerateNonceAccessKey membuat Access-Key sekali pakai: 1:key@timestamp@nonce
+func (w *SecurityAccessKey) GenerateNonceAccessKey() (string, error) {
+	nonce, err := NewNonce()
+	if err != nil {
+		return "", err
+	}
+	return w.Encrypt(FormatAccessKey(w.GetKey(), time.Now().Unix(), nonce))
+}
+
+// GenerateAccessKeyV2 membuat Access-Key v2 untuk service audience dengan scopes.
+func (w *SecurityAccessKey) GenerateAccessKeyV2(audience string, scopes ...string) (string, error) {
+	claims, err := NewAccessKeyClaims(audience, scopes...)
+	if err != nil {
+		return "", err
+	}
+	return EncryptAccessKeyClaims(w.GetKey(), claims)
+}
+
+// Validate memeriksa Access-Key: secret harus sama dengan secretKey dan timestamp
+// tidak boleh di masa depan atau lebih tua dari expireSeconds.
+func (w *SecurityAccessKey) Validate(accessKey, secretKey string, expireSeconds int64) error {
+	_, err := w.Verify(accessKey, secretKey, expireSeconds)
+	return err
+}
+
+// Verify seperti Validate, dan mengembalikan isi Access-Key untuk pemeriksaan lanjutan (nonce).
+// Access-Key v2 didekripsi dengan key turunan secretKey.
+func (w *SecurityAccessKey) Verify(accessKey, secretKey string, expireSeconds int64) (*AccessKeyClaims, error) {
+	if IsAccessKeyV2(accessKey) {
+		claims, err := DecryptAccessKeyClaims(secretKey, accessKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAccessKeyClaims(claims, secretKey, expireSeconds); err != nil {
+			return nil, err
+		}
+		return claims, nil
+	}
+
+	decryptedKey, err := w.Decrypt(accessKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessKeyInvalid, err)
+	}
+
+	claims, err := ParseAccessKey(decryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccessKeyClaims(claims, secretKey, expireSeconds); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
 // Encrypt mengenkripsi nilai persis seperti Laravel
 func (w *SecurityAccessKey) Encrypt(value string) (string, error) {
 	// 1. Generate key dengan hash (sama seperti Laravel)
-	keyHex := w.hashKey()
+	keyHex, err := w.hashKey()
+	if err != nil {
+		return "", err
+	}
 
 	// 2. Generate IV (sama seperti Laravel)
 	// PENTING: Laravel menggunakan 16 karakter pertama dari key hex sebagai IV
@@ -69,8 +149,12 @@ func (w *SecurityAccessKey) Encrypt(value string) (string, error) {
 
 	// 4. Encrypt dengan AES-CBC
 	// PENTING: Gunakan ASCII bytes dari hex string seperti yang dilakukan PHP!
-	// Ambil 32 byte pertama dari ASCII string hex untuk key
-	keyBytes := []byte(keyHex)[:32]
+	// Ambil byte pertama dari ASCII string hex sepanjang ukuran key cipher
+	keySize, err := w.keySize()
+	if err != nil {
+		return "", err
+	}
+	keyBytes := []byte(keyHex)[:keySize]
 
 	// Gunakan semua 16 byte ASCII dari string hex untuk IV
 	ivBytes := []byte(ivHex)
@@ -98,10 +182,15 @@ func (w *SecurityAccessKey) Encrypt(value string) (string, error) {
 	return encodedValue, nil
 }
 
-// Decrypt mendekripsi nilai persis seperti Laravel
+// Decrypt mendekripsi nilai persis seperti Laravel. Input yang rusak tidak
+// pernah panic; error membungkus ErrDecryptBase64, ErrDecryptLength,
+// ErrDecryptPadding atau ErrDecryptSerialization.
 func (w *SecurityAccessKey) Decrypt(value string) (string, error) {
 	// 1. Generate key dengan hash
-	keyHex := w.hashKey()
+	keyHex, err := w.hashKey()
+	if err != nil {
+		return "", err
+	}
 
 	// 2. Generate IV
 	ivHex := keyHex[:16]
@@ -109,20 +198,26 @@ func (w *SecurityAccessKey) Decrypt(value string) (string, error) {
 	// 3. Base64 decode (sama seperti Laravel)
 	decodedValue, err := base64.StdEncoding.DecodeString(value)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode base64 (outer): %v", err)
+		return "", fmt.Errorf("%w (outer): %v", ErrDecryptBase64, err)
 	}
 
 	// 3.1 Decode lagi (Laravel melakukan base64 encode dua kali)
-	decodedStr := string(decodedValue)
-	ciphertext, err := base64.StdEncoding.DecodeString(decodedStr)
+	ciphertext, err := base64.StdEncoding.DecodeString(string(decodedValue))
 	if err != nil {
-		return "", fmt.Errorf("failed to decode base64 (inner): %v", err)
+		return "", fmt.Errorf("%w (inner): %v", ErrDecryptBase64, err)
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("%w: %d bytes is not a multiple of the block size", ErrDecryptLength, len(ciphertext))
 	}
 
 	// 4. Decrypt dengan AES-CBC
 	// PENTING: Gunakan ASCII bytes dari hex string seperti yang dilakukan PHP!
-	// Ambil 32 byte pertama dari ASCII string hex untuk key
-	keyBytes := []byte(keyHex)[:32]
+	// Ambil byte pertama dari ASCII string hex sepanjang ukuran key cipher
+	keySize, err := w.keySize()
+	if err != nil {
+		return "", err
+	}
+	keyBytes := []byte(keyHex)[:keySize]
 
 	// Gunakan semua 16 byte ASCII dari string hex untuk IV
 	ivBytes := []byte(ivHex)
@@ -134,87 +229,89 @@ func (w *SecurityAccessKey) Decrypt(value string) (string, error) {
 	}
 
 	// 4.2 Decrypt
-	if len(ciphertext)%aes.BlockSize != 0 {
-		return "", fmt.Errorf("ciphertext is not a multiple of block size")
-	}
-
 	decrypted := make([]byte, len(ciphertext))
 	mode := cipher.NewCBCDecrypter(block, ivBytes)
 	mode.CryptBlocks(decrypted, ciphertext)
 
-	// 4.3 Unpad dengan PKCS#7
-	unpaddedData, err := w.pkcs7Unpad(decrypted)
+	// 4.3 Unpad dengan PKCS#7; padding salah biasanya berarti key salah
+	unpaddedData, err := pkcs7Unpad(decrypted, aes.BlockSize)
 	if err != nil {
-		log.Printf("Warning: unpadding error: %v, attempting to continue", err)
-		// Try to continue even with padding error
-		unpaddedData = decrypted
+		return "", err
 	}
 
 	decryptedStr := string(unpaddedData)
 
-	// 5. Unserialize
+	// 5. Unserialize (nilai dari encryptString Laravel tidak diserialisasi)
 	if !strings.HasPrefix(decryptedStr, "s:") {
 		return decryptedStr, nil
 	}
 
 	unserializedValue, err := w.phpUnserialize(decryptedStr)
 	if err != nil {
-		log.Printf("Warning: unserialize error: %v, returning raw value", err)
-		return decryptedStr, nil
+		return "", fmt.Errorf("%w: %v", ErrDecryptSerialization, err)
 	}
 
 	return unserializedValue, nil
 }
 
+// EncryptString implements Encrypter.
+func (w *SecurityAccessKey) EncryptString(value string) (string, error) {
+	return w.Encrypt(value)
+}
+
+// DecryptString implements Encrypter.
+func (w *SecurityAccessKey) DecryptString(value string) (string, error) {
+	return w.Decrypt(value)
+}
+
 // hashKey menghasilkan hash key persis seperti Laravel
-func (w *SecurityAccessKey) hashKey() string {
-	// PHP: hash('sha256', config('srcservice.key'))
-	hasher := sha256.New()
+func (w *SecurityAccessKey) hashKey() (string, error) {
+	// PHP: hash(config('srcservice.hash'), config('srcservice.key'))
+	fn, err := LookupHash(w.GetHash())
+	if err != nil {
+		return "", err
+	}
+	hasher := fn()
 	hasher.Write([]byte(w.GetKey()))
-	return hex.EncodeToString(hasher.Sum(nil))
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// keySize mengembalikan ukuran key cipher. Hanya AES-CBC yang didukung: IV
+// Access-Key diturunkan dari key, sehingga mode AEAD akan memakai ulang nonce.
+func (w *SecurityAccessKey) keySize() (int, error) {
+	return accessKeyCipherSize(w.GetCipher())
+}
+
+func accessKeyCipherSize(cipherMode string) (int, error) {
+	spec, err := LookupCipher(cipherMode)
+	if err != nil {
+		return 0, err
+	}
+	if spec.AEAD {
+		return 0, fmt.Errorf("cipher %q is not supported for access keys, use aes-128-cbc or aes-256-cbc", cipherMode)
+	}
+	return spec.KeySize, nil
 }
 
 // phpSerialize mengimplementasikan PHP serialize() untuk string
 func (w *SecurityAccessKey) phpSerialize(value string) string {
 	// Format: s:length:"content";
-	return fmt.Sprintf("s:%d:\"%s\";", len(value), value)
+	var b strings.Builder
+	phpEncodeString(&b, value)
+	return b.String()
 }
 
 // phpUnserialize mengimplementasikan PHP unserialize() untuk string
 func (w *SecurityAccessKey) phpUnserialize(value string) (string, error) {
 	// Expected format: s:length:"content";
-	if !strings.HasPrefix(value, "s:") {
-		return "", fmt.Errorf("not a serialized string")
-	}
-
-	parts := strings.SplitN(value, ":", 3)
-	if len(parts) < 3 {
-		return "", fmt.Errorf("invalid format")
-	}
-
-	// Get length
-	lengthStr := parts[1]
-	length, err := strconv.Atoi(lengthStr)
+	unserialized, err := PHPUnserialize(value)
 	if err != nil {
-		return "", fmt.Errorf("invalid length")
+		return "", err
 	}
-
-	// Extract content
-	contentParts := strings.SplitN(parts[2], ";", 2)
-	if len(contentParts) < 1 {
-		return "", fmt.Errorf("invalid content format")
-	}
-
-	content := contentParts[0]
-	if strings.HasPrefix(content, "\"") && strings.HasSuffix(content, "\"") {
-		content = content[1 : len(content)-1]
-	}
-
-	// Verify length
-	if len(content) != length {
-		return "", fmt.Errorf("content length mismatch, expected %d, got %d", length, len(content))
+	content, ok := unserialized.(string)
+	if !ok {
+		return "", fmt.Errorf("not a serialized string")
 	}
-
 	return content, nil
 }
 
@@ -225,28 +322,38 @@ func (w *SecurityAccessKey) pkcs7Pad(data []byte, blockSize int) []byte {
 	return append(data, padtext...)
 }
 
-// pkcs7Unpad removes PKCS#7 padding
-func (w *SecurityAccessKey) pkcs7Unpad(data []byte) ([]byte, error) {
-	length := len(data)
-	if length == 0 {
-		return nil, fmt.Errorf("empty data")
-	}
+// NewSecurityAccessKey creates a new instance
+func NewSecurityAccessKey() *SecurityAccessKey {
+	return &SecurityAccessKey{}
+}
 
-	padding := int(data[length-1])
-	if padding > length || padding == 0 {
-		return nil, fmt.Errorf("invalid padding size")
+// NewSecurityAccessKeyWithConfig creates an instance with an explicit key, hash
+// and cipher, rejecting unregistered hashes and ciphers other than AES-CBC.
+func NewSecurityAccessKeyWithConfig(key, hashMethod, cipherMode string) (*SecurityAccessKey, error) {
+	if _, err := LookupHash(hashMethod); err != nil {
+		return nil, err
 	}
-
-	for i := length - padding; i < length; i++ {
-		if data[i] != byte(padding) {
-			return nil, fmt.Errorf("invalid padding")
-		}
+	if _, err := accessKeyCipherSize(cipherMode); err != nil {
+		return nil, err
 	}
+	return &SecurityAccessKey{key: key, hash: hashMethod, cipher: cipherMode}, nil
+}
 
-	return data[:length-padding], nil
+// NewSecurityAccessKeyFromEnv is NewSecurityAccessKeyWithConfig with APP_KEY,
+// APP_HASH and APP_CIPHER, so a bad configuration fails at startup.
+func NewSecurityAccessKeyFromEnv() (*SecurityAccessKey, error) {
+	w := NewSecurityAccessKey()
+	return NewSecurityAccessKeyWithConfig(w.GetKey(), w.GetHash(), w.GetCipher())
 }
 
-// NewSecurityAccessKey creates a new instance
-func NewSecurityAccessKey() *SecurityAccessKey {
-	return &SecurityAccessKey{}
+// NewSecurityAccessKeyWithKey creates an instance using key instead of APP_KEY,
+// e.g. a previous key of a Keyring.
+func NewSecurityAccessKeyWithKey(key string) *SecurityAccessKey {
+	return &SecurityAccessKey{key: key}
+}
+
+// WithKey returns a copy using key, keeping the hash and cipher of w. Copies of
+// an instance from NewSecurityAccessKeyFromEnv stay validated.
+func (w *SecurityAccessKey) WithKey(key string) *SecurityAccessKey {
+	return &SecurityAccessKey{key: key, hash: w.hash, cipher: w.cipher}
 }
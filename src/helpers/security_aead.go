@@ -0,0 +1,131 @@
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// AEAD cipher modes accepted by NewSecurity in addition to those in encrypter.go.
+const (
+	CipherAES256GCM        = "aes-256-gcm"
+	CipherChaCha20Poly1305 = "chacha20-poly1305"
+)
+
+// aeadPrefix marks the versioned AEAD format: "v2:<alg>:<base64(nonce || ciphertext)>".
+// Legacy CBC values are plain base64, which never contains ':'.
+const aeadPrefix = "v2:"
+
+var aeadTags = map[string]string{
+	CipherAES128GCM:        "gcm",
+	CipherAES256GCM:        "gcm",
+	CipherChaCha20Poly1305: "chacha",
+}
+
+// isAEADMode reports whether cipherMode selects the versioned AEAD format.
+func isAEADMode(cipherMode string) bool {
+	_, ok := aeadTags[strings.ToLower(cipherMode)]
+	return ok
+}
+
+// decodedKey strips the optional "base64:" prefix and decodes the key, which
+// must match the key size of the configured cipher.
+func (s *Security) decodedKey() ([]byte, error) {
+	key := strings.TrimPrefix(s.key, "base64:")
+	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding key: %v", err)
+	}
+	spec, err := s.cipherSpec()
+	if err != nil {
+		return nil, err
+	}
+	if len(decodedKey) != spec.KeySize {
+		return nil, fmt.Errorf("invalid key length, expected %d bytes, got %d bytes", spec.KeySize, len(decodedKey))
+	}
+	return decodedKey, nil
+}
+
+func newAEAD(tag string, key []byte) (cipher.AEAD, error) {
+	switch tag {
+	case "gcm":
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case "chacha":
+		return chacha20poly1305.New(key)
+	}
+	return nil, fmt.Errorf("unknown AEAD algorithm %q", tag)
+}
+
+// encryptAEAD serializes value and seals it with a random nonce.
+func (s *Security) encryptAEAD(value interface{}) (string, error) {
+	serializedValue, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	key, err := s.decodedKey()
+	if err != nil {
+		return "", err
+	}
+
+	tag := aeadTags[strings.ToLower(s.cipherMode)]
+	aead, err := newAEAD(tag, key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(serializedValue)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	// The prefix is authenticated too, so the algorithm tag cannot be swapped.
+	header := aeadPrefix + tag + ":"
+	sealed := aead.Seal(nonce, nonce, serializedValue, []byte(header))
+	return header + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptAEAD opens a value produced by encryptAEAD, whatever the configured cipher mode.
+func (s *Security) decryptAEAD(encryptedValue string) (interface{}, error) {
+	tag, payload, found := strings.Cut(strings.TrimPrefix(encryptedValue, aeadPrefix), ":")
+	if !found {
+		return nil, fmt.Errorf("%w: malformed header", ErrDecryptBase64)
+	}
+
+	key, err := s.decodedKey()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(tag, key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptBase64, err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptBase64, err)
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("%w: %d bytes is shorter than nonce and tag", ErrDecryptLength, len(data))
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(aeadPrefix+tag+":"))
+	if err != nil {
+		return nil, ErrDecryptMAC
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(plaintext, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptSerialization, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSecurityDecryptErrors(t *testing.T) {
+	cbc, err := NewSecurityWithConfig(HashSHA256, laravelTestKey, CipherAES256CBC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := NewSecurityWithConfig(HashSHA256, laravelTestKey, CipherAES256GCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := gcm.Encrypt("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := sealed[:len(sealed)-2] + strings.Map(func(r rune) rune {
+		if r == 'A' {
+			return 'B'
+		}
+		return 'A'
+	}, sealed[len(sealed)-2:len(sealed)-1]) + sealed[len(sealed)-1:]
+
+	tests := []struct {
+		name     string
+		security *Security
+		value    string
+		want     error
+	}{
+		{"not base64", cbc, "%%%", ErrDecryptBase64},
+		{"empty", cbc, "", ErrDecryptLength},
+		{"unaligned", cbc, base64.StdEncoding.EncodeToString(make([]byte, 5)), ErrDecryptLength},
+		{"aead tampered", gcm, tampered, ErrDecryptMAC},
+		{"aead short", gcm, "v2:gcm:" + base64.StdEncoding.EncodeToString(make([]byte, 8)), ErrDecryptLength},
+		{"aead bad header", gcm, "v2:gcm", ErrDecryptBase64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.security.Decrypt(tt.value); !errors.Is(err, tt.want) {
+				t.Fatalf("Decrypt error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityAccessKeyDecryptErrors(t *testing.T) {
+	security, err := NewSecurityAccessKeyWithConfig("secret", HashSHA256, CipherAES256CBC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twice := func(data []byte) string {
+		return base64.StdEncoding.EncodeToString([]byte(base64.StdEncoding.EncodeToString(data)))
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  error
+	}{
+		{"outer base64", "%%%", ErrDecryptBase64},
+		{"inner base64", base64.StdEncoding.EncodeToString([]byte("%%%")), ErrDecryptBase64},
+		{"unaligned", twice(make([]byte, 5)), ErrDecryptLength},
+		{"bad padding", twice(make([]byte, 32)), ErrDecryptPadding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := security.Decrypt(tt.value); !errors.Is(err, tt.want) {
+				t.Fatalf("Decrypt error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	encrypted, err := security.Encrypt("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := security.Decrypt(encrypted); err != nil || value != "hello" {
+		t.Fatalf("Decrypt = %q, %v", value, err)
+	}
+}
@@ -0,0 +1,223 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrURLSignatureInvalid means the signature is missing or was not made with a known key.
+	ErrURLSignatureInvalid = errors.New("signed url: invalid signature")
+	// ErrURLSignatureExpired means the "expires" timestamp has passed.
+	ErrURLSignatureExpired = errors.New("signed url: expired")
+)
+
+// URLSigner creates and verifies links compatible with Laravel's
+// URL::signedRoute and URL::temporarySignedRoute: the "signature" query
+// parameter is hash_hmac('sha256', $url, config('app.key')) and "expires" is a
+// Unix timestamp.
+type URLSigner struct {
+	// Keys are tried in order when verifying; the first one signs. Like
+	// Laravel they are used as configured, "base64:" prefix included.
+	Keys []string
+	// TrustProxyHeaders makes VerifyRequest rebuild absolute URLs from
+	// X-Forwarded-Proto and X-Forwarded-Host, like Laravel's TrustProxies.
+	TrustProxyHeaders bool
+	Now               func() time.Time
+}
+
+// NewURLSigner creates a signer for key; previousKeys are only used to verify.
+func NewURLSigner(key string, previousKeys ...string) *URLSigner {
+	return &URLSigner{Keys: append([]string{key}, previousKeys...), Now: time.Now}
+}
+
+// NewURLSignerFromEnv uses APP_KEY and APP_PREVIOUS_KEYS.
+func NewURLSignerFromEnv() *URLSigner {
+	return NewURLSigner(GetEnv("APP_KEY", ""), previousKeysFromEnv()...)
+}
+
+// previousKeysFromEnv splits the comma separated APP_PREVIOUS_KEYS.
+func previousKeysFromEnv() []string {
+	var previous []string
+	for _, item := range strings.Split(GetEnv("APP_PREVIOUS_KEYS", ""), ",") {
+		if key := strings.TrimSpace(item); key != "" {
+			previous = append(previous, key)
+		}
+	}
+	return previous
+}
+
+// Sign appends params, sorted by name, and the signature to base, which is an
+// absolute URL or, for relative signatures, a path.
+func (s *URLSigner) Sign(base string, params map[string]string) (string, error) {
+	if err := s.checkSign(params); err != nil {
+		return "", err
+	}
+	return s.sign(base, params), nil
+}
+
+// TemporarySign is Sign with an "expires" parameter, like temporarySignedRoute.
+func (s *URLSigner) TemporarySign(base string, expiration time.Time, params map[string]string) (string, error) {
+	if err := s.checkSign(params); err != nil {
+		return "", err
+	}
+	withExpiry := make(map[string]string, len(params)+1)
+	for name, value := range params {
+		withExpiry[name] = value
+	}
+	withExpiry["expires"] = strconv.FormatInt(expiration.Unix(), 10)
+	return s.sign(base, withExpiry), nil
+}
+
+func (s *URLSigner) checkSign(params map[string]string) error {
+	if len(s.Keys) == 0 || s.Keys[0] == "" {
+		return errors.New("signed url: no application key has been specified")
+	}
+	for _, reserved := range []string{"signature", "expires"} {
+		if _, ok := params[reserved]; ok {
+			return fmt.Errorf("signed url: %q is a reserved parameter", reserved)
+		}
+	}
+	return nil
+}
+
+func (s *URLSigner) sign(base string, params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, rawURLEncode(name)+"="+rawURLEncode(params[name]))
+	}
+
+	unsigned := base
+	if len(pairs) > 0 {
+		separator := "?"
+		if strings.Contains(base, "?") {
+			separator = "&"
+		}
+		unsigned += separator + strings.Join(pairs, "&")
+	}
+
+	separator := "?"
+	if strings.Contains(unsigned, "?") {
+		separator = "&"
+	}
+	return unsigned + separator + "signature=" + urlSignature(unsigned, s.Keys[0])
+}
+
+// Verify checks a signed link as received: an absolute URL, or a path with
+// query for relative signatures. Query parameters named in ignoreQuery are left
+// out of the signature check, like hasValidSignatureWhileIgnoring.
+func (s *URLSigner) Verify(signedURL string, ignoreQuery ...string) error {
+	base, rawQuery, _ := strings.Cut(signedURL, "?")
+	return s.verify(base, rawQuery, ignoreQuery)
+}
+
+// VerifyRequest checks the signature of r the way Laravel's ValidateSignature
+// middleware does, against the absolute URL or, when relative, the path only.
+func (s *URLSigner) VerifyRequest(r *http.Request, relative bool, ignoreQuery ...string) error {
+	path := strings.TrimRight(r.URL.EscapedPath(), "/")
+	if relative {
+		// '/'.$request->path(), where path() of the root is "/".
+		relativePath := strings.TrimLeft(path, "/")
+		if relativePath == "" {
+			relativePath = "/"
+		}
+		return s.verify("/"+relativePath, r.URL.RawQuery, ignoreQuery)
+	}
+
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if s.TrustProxyHeaders {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme, _, _ = strings.Cut(proto, ",")
+		}
+		if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+			host, _, _ = strings.Cut(forwarded, ",")
+		}
+	}
+	return s.verify(strings.TrimSpace(scheme)+"://"+strings.TrimSpace(host)+path, r.URL.RawQuery, ignoreQuery)
+}
+
+func (s *URLSigner) verify(base, rawQuery string, ignoreQuery []string) error {
+	ignored := map[string]bool{"signature": true}
+	for _, name := range ignoreQuery {
+		ignored[name] = true
+	}
+
+	// Like Laravel, the signed query string is the raw one minus ignored parameters.
+	var kept []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if !ignored[name] {
+			kept = append(kept, pair)
+		}
+	}
+	original := strings.TrimRight(base+"?"+strings.Join(kept, "&"), "?")
+
+	query, _ := url.ParseQuery(rawQuery)
+	signature := query.Get("signature")
+
+	valid := false
+	for _, key := range s.Keys {
+		if key != "" && hmac.Equal([]byte(urlSignature(original, key)), []byte(signature)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrURLSignatureInvalid
+	}
+
+	if expires := query.Get("expires"); expires != "" {
+		timestamp, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: malformed expires", ErrURLSignatureInvalid)
+		}
+		if s.now().Unix() > timestamp {
+			return ErrURLSignatureExpired
+		}
+	}
+	return nil
+}
+
+func (s *URLSigner) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func urlSignature(value, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// rawURLEncode is PHP's rawurlencode, which http_build_query uses with PHP_QUERY_RFC3986.
+func rawURLEncode(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
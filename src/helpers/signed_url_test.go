@@ -0,0 +1,157 @@
+package helpers
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// The signatures below are hash_hmac('sha256', $url, $key) for laravelTestKey,
+// the "base64:" prefix included as Laravel uses it, computed with
+// `printf '%s' "$url" | openssl dgst -sha256 -hmac "$key"` over the URLs
+// URL::temporarySignedRoute builds; they were not produced by PHP itself.
+const (
+	laravelSignedURL         = "https://sim-mbkm.test/registrations/42?expires=1700000000&program=MSIB%20Batch%206&signature=f98e2824bd58959e0bd9f5679577df5fd0f1623e16c412cd7a91cc40fb4e93b5"
+	laravelRelativeSignedURL = "/registrations/42?expires=1700000000&program=MSIB%20Batch%206&signature=4743b3580cb9360887a72ba3c1701781f8b2004e6e46f5d91458d3c396b1f275"
+	// laravelRootSignature signs "//", what ValidateSignature compares for a
+	// relative signature of the root path, '/'.$request->path().
+	laravelRootSignature = "e263f2ca9fafef1ea2164c078b4b40afb20c7ff4994d2a8ed77a6432df8587db"
+	// laravelAbsoluteRootSignature signs "http://sim-mbkm.test", $request->url()
+	// of the root without its trailing slash.
+	laravelAbsoluteRootSignature = "9359c7b0e8cf38eefbd14dd6edd6dd1a817b3a52f00a61d584a3bb2825ee4553"
+)
+
+func newTestURLSigner(now time.Time, previousKeys ...string) *URLSigner {
+	signer := NewURLSigner(laravelTestKey, previousKeys...)
+	signer.Now = func() time.Time { return now }
+	return signer
+}
+
+func TestURLSignerLaravelVector(t *testing.T) {
+	signer := newTestURLSigner(time.Unix(1699999000, 0))
+	params := map[string]string{"program": "MSIB Batch 6"}
+
+	signed, err := signer.TemporarySign("https://sim-mbkm.test/registrations/42", time.Unix(1700000000, 0), params)
+	if err != nil || signed != laravelSignedURL {
+		t.Fatalf("TemporarySign() = %q, %v, want %q", signed, err, laravelSignedURL)
+	}
+	relative, err := signer.TemporarySign("/registrations/42", time.Unix(1700000000, 0), params)
+	if err != nil || relative != laravelRelativeSignedURL {
+		t.Fatalf("TemporarySign() relative = %q, %v, want %q", relative, err, laravelRelativeSignedURL)
+	}
+
+	for _, signedURL := range []string{laravelSignedURL, laravelRelativeSignedURL} {
+		if err := signer.Verify(signedURL); err != nil {
+			t.Fatalf("Verify(%q) = %v", signedURL, err)
+		}
+	}
+}
+
+func TestURLSignerSignReserved(t *testing.T) {
+	signer := newTestURLSigner(time.Unix(1700000000, 0))
+	for _, name := range []string{"signature", "expires"} {
+		if _, err := signer.Sign("/registrations", map[string]string{name: "1"}); err == nil {
+			t.Fatalf("Sign() accepted the reserved %q parameter", name)
+		}
+	}
+	if _, err := NewURLSigner("").Sign("/registrations", nil); err == nil {
+		t.Fatal("Sign() without a key succeeded")
+	}
+}
+
+func TestURLSignerVerify(t *testing.T) {
+	const previousKey = "base64:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+	before, after := time.Unix(1699999000, 0), time.Unix(1700000001, 0)
+	previous, err := NewURLSigner(previousKey).TemporarySign("/registrations/42", time.Unix(1700000000, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	permanent, err := NewURLSigner(laravelTestKey).Sign("/registrations?page=2", map[string]string{"program": "MSIB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		url    string
+		ignore []string
+		want   error
+	}{
+		{"valid", before, laravelSignedURL, nil, nil},
+		{"existing query", before, permanent, nil, nil},
+		{"no expiry never expires", after, permanent, nil, nil},
+		{"tampered parameter", before, "https://sim-mbkm.test/registrations/43?expires=1700000000&program=MSIB%20Batch%206&signature=f98e2824bd58959e0bd9f5679577df5fd0f1623e16c412cd7a91cc40fb4e93b5", nil, ErrURLSignatureInvalid},
+		{"tampered host", before, "https://evil.test/registrations/42?expires=1700000000&program=MSIB%20Batch%206&signature=f98e2824bd58959e0bd9f5679577df5fd0f1623e16c412cd7a91cc40fb4e93b5", nil, ErrURLSignatureInvalid},
+		{"extended expiry", before, "https://sim-mbkm.test/registrations/42?expires=1800000000&program=MSIB%20Batch%206&signature=f98e2824bd58959e0bd9f5679577df5fd0f1623e16c412cd7a91cc40fb4e93b5", nil, ErrURLSignatureInvalid},
+		{"missing signature", before, "https://sim-mbkm.test/registrations/42?expires=1700000000&program=MSIB%20Batch%206", nil, ErrURLSignatureInvalid},
+		{"added parameter", before, laravelSignedURL + "&utm_source=mail", nil, ErrURLSignatureInvalid},
+		{"ignored parameter", before, laravelSignedURL + "&utm_source=mail", []string{"utm_source"}, nil},
+		{"ignored signed parameter", before, laravelSignedURL, []string{"program"}, ErrURLSignatureInvalid},
+		{"previous key", before, previous, nil, nil},
+		{"expired", after, laravelSignedURL, nil, ErrURLSignatureExpired},
+		// The signature is checked first, so a forged link never learns about expiry.
+		{"expired and tampered", after, laravelSignedURL[:len(laravelSignedURL)-1] + "0", nil, ErrURLSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := newTestURLSigner(tt.now, previousKey)
+			if err := signer.Verify(tt.url, tt.ignore...); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLSignerVerifyRequest(t *testing.T) {
+	signer := newTestURLSigner(time.Unix(1699999000, 0))
+
+	tests := []struct {
+		name     string
+		target   string
+		relative bool
+		tls      bool
+		trust    bool
+		header   map[string]string
+		want     error
+	}{
+		{"absolute", laravelSignedURL, false, true, false, nil, nil},
+		{"absolute over http", "http://sim-mbkm.test/registrations/42?expires=1700000000&program=MSIB%20Batch%206&signature=f98e2824bd58959e0bd9f5679577df5fd0f1623e16c412cd7a91cc40fb4e93b5", false, false, false, nil, ErrURLSignatureInvalid},
+		{"behind a proxy", "http://10.0.0.5:8080/registrations/42?expires=1700000000&program=MSIB%20Batch%206&signature=f98e2824bd58959e0bd9f5679577df5fd0f1623e16c412cd7a91cc40fb4e93b5", false, false, true, map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "sim-mbkm.test"}, nil},
+		{"untrusted proxy headers", "http://10.0.0.5:8080/registrations/42?expires=1700000000&program=MSIB%20Batch%206&signature=f98e2824bd58959e0bd9f5679577df5fd0f1623e16c412cd7a91cc40fb4e93b5", false, false, false, map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "sim-mbkm.test"}, ErrURLSignatureInvalid},
+		{"trailing slash", "https://sim-mbkm.test/registrations/42/?expires=1700000000&program=MSIB%20Batch%206&signature=f98e2824bd58959e0bd9f5679577df5fd0f1623e16c412cd7a91cc40fb4e93b5", false, true, false, nil, nil},
+		{"relative", "http://other.test" + laravelRelativeSignedURL, true, false, false, nil, nil},
+		{"relative signature checked as absolute", "http://other.test" + laravelRelativeSignedURL, false, false, false, nil, ErrURLSignatureInvalid},
+		{"relative root", "http://sim-mbkm.test/?signature=" + laravelRootSignature, true, false, false, nil, nil},
+		{"absolute root", "http://sim-mbkm.test/?signature=" + laravelAbsoluteRootSignature, false, false, false, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			if !tt.tls {
+				r.TLS = nil
+			} else if r.TLS == nil {
+				r.TLS = &tls.ConnectionState{}
+			}
+			for name, value := range tt.header {
+				r.Header.Set(name, value)
+			}
+			signer.TrustProxyHeaders = tt.trust
+			if err := signer.VerifyRequest(r, tt.relative); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("VerifyRequest() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Like Laravel, a relative link signed for "/" does not verify on the root,
+	// which is compared as "//".
+	root, err := signer.Sign("/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.VerifyRequest(httptest.NewRequest("GET", "http://sim-mbkm.test"+root, nil), true); !errors.Is(err, ErrURLSignatureInvalid) {
+		t.Fatalf("VerifyRequest() root signed as / = %v, want ErrURLSignatureInvalid", err)
+	}
+}
@@ -0,0 +1,49 @@
+# Generates the fixed payloads of TestLaravelDecryptFixtures without PHP or Go:
+# AES comes from the openssl CLI and each step follows
+# Illuminate\Encryption\Encrypter::encrypt (Laravel 11). GCM is assembled from
+# AES-CTR and GHASH because `openssl enc` has no AEAD modes.
+#
+#     python3 src/helpers/testdata/laravel_payloads.py
+import base64, hmac, hashlib, json, subprocess
+
+key = base64.b64decode("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+
+def openssl(args, data):
+    return subprocess.run(["openssl", "enc"] + args + ["-K", key.hex()], input=data, capture_output=True, check=True).stdout
+
+def ecb(block):
+    return openssl(["-aes-256-ecb", "-nopad"], block)
+
+def php_json(d):
+    # json_encode(compact('iv','value','mac','tag'), JSON_UNESCAPED_SLASHES)
+    return json.dumps(d, separators=(",", ":")).encode()
+
+# AES-256-CBC, encrypt($value) serializes first.
+iv = bytes(range(16))
+plain = b's:10:"5025201001";'
+value = base64.b64encode(openssl(["-aes-256-cbc", "-iv", iv.hex()], plain)).decode()
+ivb = base64.b64encode(iv).decode()
+mac = hmac.new(key, (ivb + value).encode(), hashlib.sha256).hexdigest()
+print("CBC", base64.b64encode(php_json({"iv": ivb, "value": value, "mac": mac, "tag": ""})).decode())
+
+# AES-256-GCM, encryptString($value): 12 byte iv, no AAD, 16 byte tag.
+def gf_mult(x, y):
+    R = 0xE1 << 120
+    z = 0
+    for i in range(127, -1, -1):
+        if (y >> i) & 1:
+            z ^= x
+        x = (x >> 1) ^ R if x & 1 else x >> 1
+    return z
+
+iv = bytes(range(100, 112))
+plain = b"Zq3bW1mHkP8sT2vX9yL4nR6cJ0aE5dF7gU1iO3pQ"
+H = int.from_bytes(ecb(b"\0" * 16), "big")
+j0 = iv + b"\0\0\0\1"
+ct = openssl(["-aes-256-ctr", "-iv", (iv + b"\0\0\0\2").hex()], plain)
+padded = ct + b"\0" * (-len(ct) % 16) + (0).to_bytes(8, "big") + (len(ct) * 8).to_bytes(8, "big")
+y = 0
+for i in range(0, len(padded), 16):
+    y = gf_mult(y ^ int.from_bytes(padded[i:i+16], "big"), H)
+tag = bytes(a ^ b for a, b in zip(ecb(j0), y.to_bytes(16, "big")))
+print("GCM", base64.b64encode(php_json({"iv": base64.b64encode(iv).decode(), "value": base64.b64encode(ct).decode(), "mac": "", "tag": base64.b64encode(tag).decode()})).decode())
@@ -0,0 +1,324 @@
+package helpers
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed lang/*.json
+var defaultMessages embed.FS
+
+// DefaultFallbackLocale is used when a message is missing in the requested locale.
+// It matches the language of the messages this package returned historically.
+const DefaultFallbackLocale = "id"
+
+// Translator resolves message IDs into localized strings using Laravel style
+// ":param" placeholders and "singular|plural" choices.
+type Translator struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+	fallback []string
+}
+
+var (
+	defaultTranslator     *Translator
+	defaultTranslatorOnce sync.Once
+)
+
+// NewTranslator creates an empty Translator with the given fallback chain.
+func NewTranslator(fallback ...string) *Translator {
+	return &Translator{
+		messages: map[string]map[string]string{},
+		fallback: fallback,
+	}
+}
+
+// DefaultTranslator returns the shared Translator preloaded with the package messages.
+// Services can add their own catalogs to it with LoadDir or Add.
+func DefaultTranslator() *Translator {
+	defaultTranslatorOnce.Do(func() {
+		defaultTranslator = NewTranslator(DefaultFallbackLocale, "en")
+		entries, _ := defaultMessages.ReadDir("lang")
+		for _, entry := range entries {
+			data, err := defaultMessages.ReadFile("lang/" + entry.Name())
+			if err != nil {
+				continue
+			}
+			locale := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if err := defaultTranslator.AddData(locale, data, json.Unmarshal); err != nil {
+				panic(fmt.Sprintf("helpers: invalid embedded messages %s: %v", entry.Name(), err))
+			}
+		}
+	})
+	return defaultTranslator
+}
+
+// T translates id with the default translator in the locale carried by ctx.
+func T(ctx context.Context, id string, params map[string]interface{}) string {
+	return DefaultTranslator().Translate(LocaleFromContext(ctx), id, params)
+}
+
+// Add merges flat messages for locale into the catalog.
+func (t *Translator) Add(locale string, messages map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	locale = normalizeLocale(locale)
+	if t.messages[locale] == nil {
+		t.messages[locale] = map[string]string{}
+	}
+	for id, message := range messages {
+		t.messages[locale][id] = message
+	}
+}
+
+// AddData decodes a nested message document and merges it for locale.
+// Nested keys are joined with ".", so {"auth": {"failed": "..."}} defines "auth.failed".
+func (t *Translator) AddData(locale string, data []byte, unmarshal func([]byte, interface{}) error) error {
+	var document map[string]interface{}
+	if err := unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	messages := map[string]string{}
+	flattenMessages("", document, messages)
+	t.Add(locale, messages)
+	return nil
+}
+
+// LoadFile loads a JSON or YAML catalog for locale.
+func (t *Translator) LoadFile(locale, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return t.AddData(locale, data, json.Unmarshal)
+	case ".yaml", ".yml":
+		return t.AddData(locale, data, yaml.Unmarshal)
+	}
+	return fmt.Errorf("unsupported translation file %s", path)
+}
+
+// LoadDir loads every <locale>.json, <locale>.yaml and <locale>.yml file in dir.
+func (t *Translator) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		locale := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if err := t.LoadFile(locale, filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("error loading %s: %v", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Translate returns the message for id in locale with params interpolated.
+// Unknown IDs are returned unchanged.
+func (t *Translator) Translate(locale, id string, params map[string]interface{}) string {
+	message, found := t.lookup(locale, id)
+	if !found {
+		return id
+	}
+	if strings.Contains(message, "|") {
+		message = selectPlural(message, 1, locale)
+	}
+	return interpolate(message, params)
+}
+
+// Choice returns the plural form of id matching count. The count is available as ":count".
+func (t *Translator) Choice(locale, id string, count int, params map[string]interface{}) string {
+	message, found := t.lookup(locale, id)
+	if !found {
+		return id
+	}
+
+	merged := map[string]interface{}{"count": count}
+	for key, value := range params {
+		merged[key] = value
+	}
+	return interpolate(selectPlural(message, count, locale), merged)
+}
+
+// lookup walks locale, its base language and the fallback chain.
+func (t *Translator) lookup(locale, id string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	chain := []string{normalizeLocale(locale)}
+	if base, _, found := strings.Cut(chain[0], "-"); found {
+		chain = append(chain, base)
+	}
+	for _, fallback := range t.fallback {
+		chain = append(chain, normalizeLocale(fallback))
+	}
+
+	for _, candidate := range chain {
+		if message, ok := t.messages[candidate][id]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+func flattenMessages(prefix string, document map[string]interface{}, messages map[string]string) {
+	for key, value := range document {
+		id := key
+		if prefix != "" {
+			id = prefix + "." + key
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flattenMessages(id, value, messages)
+		case string:
+			messages[id] = value
+		default:
+			messages[id] = fmt.Sprint(value)
+		}
+	}
+}
+
+// interpolate replaces :name, :Name and :NAME placeholders like Laravel's translator.
+// Longer keys are replaced first so ":attribute" wins over ":attr".
+func interpolate(message string, params map[string]interface{}) string {
+	if len(params) == 0 {
+		return message
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	replacements := make([]string, 0, len(keys)*6)
+	for _, key := range keys {
+		value := fmt.Sprint(params[key])
+		replacements = append(replacements,
+			":"+strings.ToUpper(key), strings.ToUpper(value),
+			":"+upperFirst(key), upperFirst(value),
+			":"+key, value,
+		)
+	}
+	return strings.NewReplacer(replacements...).Replace(message)
+}
+
+// selectPlural implements Laravel's MessageSelector: explicit "{n}" and "[min,max]"
+// ranges are tried first, then the plural index of the locale.
+func selectPlural(message string, count int, locale string) string {
+	segments := strings.Split(message, "|")
+
+	for _, segment := range segments {
+		if text, ok := matchPluralRange(segment, count); ok {
+			return text
+		}
+	}
+
+	for i, segment := range segments {
+		segments[i] = stripPluralRange(segment)
+	}
+	index := pluralIndex(locale, count)
+	if index >= len(segments) {
+		index = 0
+	}
+	return segments[index]
+}
+
+func matchPluralRange(segment string, count int) (string, bool) {
+	segment = strings.TrimSpace(segment)
+	if segment == "" {
+		return "", false
+	}
+
+	switch segment[0] {
+	case '{':
+		end := strings.Index(segment, "}")
+		if end < 0 {
+			return "", false
+		}
+		text := strings.TrimSpace(segment[end+1:])
+		for _, value := range strings.Split(segment[1:end], ",") {
+			if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n == count {
+				return text, true
+			}
+		}
+	case '[':
+		end := strings.Index(segment, "]")
+		if end < 0 {
+			return "", false
+		}
+		from, to, found := strings.Cut(segment[1:end], ",")
+		if !found {
+			return "", false
+		}
+		text := strings.TrimSpace(segment[end+1:])
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		lower, errFrom := strconv.Atoi(from)
+		upper, errTo := strconv.Atoi(to)
+		if (from == "*" || (errFrom == nil && count >= lower)) && (to == "*" || (errTo == nil && count <= upper)) {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func stripPluralRange(segment string) string {
+	segment = strings.TrimSpace(segment)
+	if segment == "" {
+		return segment
+	}
+	if segment[0] == '{' {
+		if end := strings.Index(segment, "}"); end >= 0 {
+			return strings.TrimSpace(segment[end+1:])
+		}
+	}
+	if segment[0] == '[' {
+		if end := strings.Index(segment, "]"); end >= 0 {
+			return strings.TrimSpace(segment[end+1:])
+		}
+	}
+	return segment
+}
+
+// pluralIndex covers the languages we serve; everything else uses the English rule.
+func pluralIndex(locale string, count int) int {
+	base, _, _ := strings.Cut(normalizeLocale(locale), "-")
+	switch base {
+	case "id", "ms", "ja", "ko", "zh", "th", "vi", "tr":
+		return 0
+	case "fr", "pt":
+		if count == 0 || count == 1 {
+			return 0
+		}
+		return 1
+	}
+	if count == 1 {
+		return 0
+	}
+	return 1
+}
+
+func upperFirst(value string) string {
+	if value == "" {
+		return value
+	}
+	return strings.ToUpper(value[:1]) + value[1:]
+}
@@ -1,10 +1,10 @@
 package middleware
 
 import (
+	"fmt"
+	"log"
 	"net/http"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/SIM-MBKM/mod-service/src/helpers"
 
@@ -21,13 +21,40 @@ type FrontendConfig struct {
 	CustomHeaderValue string
 }
 
-// AccessKeyMiddleware validates the Access-Key in the request header
-func AccessKeyMiddleware(secretKey string, expireSeconds int64, frontendConfig *FrontendConfig) gin.HandlerFunc {
+// AccessKeyOption enables optional checks in AccessKeyMiddleware.
+type AccessKeyOption func(*accessKeyOptions)
+
+type accessKeyOptions struct {
+	// security decrypts Access-Keys with the APP_KEY, APP_HASH and APP_CIPHER
+	// validated when the middleware was built.
+	security  *helpers.SecurityAccessKey
+	signature *SignatureConfig
+	replay    *replayProtection
+	keyring   *helpers.Keyring
+	callers   *callerCredentials
+	csrf      *csrfVerifier
+	v2        *AccessKeyV2Config
+	routes    *helpers.RoutePolicy
+}
+
+// AccessKeyMiddleware validates the Access-Key in the request header. It panics
+// when APP_HASH or APP_CIPHER is invalid, so a bad configuration stops the
+// service at startup instead of rejecting every request.
+func AccessKeyMiddleware(secretKey string, expireSeconds int64, frontendConfig *FrontendConfig, opts ...AccessKeyOption) gin.HandlerFunc {
+	security, err := helpers.NewSecurityAccessKeyFromEnv()
+	if err != nil {
+		panic(fmt.Sprintf("middleware: invalid access key configuration: %v", err))
+	}
+	options := &accessKeyOptions{security: security}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	return func(c *gin.Context) {
 
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Access-Key")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, X-XSRF-TOKEN, Authorization, accept, origin, Cache-Control, X-Requested-With, Access-Key")
 		c.Header("Access-Control-Allow-Methods", "POST, HEAD, PATCH, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == http.MethodOptions {
@@ -39,6 +66,9 @@ func AccessKeyMiddleware(secretKey string, expireSeconds int64, frontendConfig *
 		if frontendConfig != nil && isFrontendRequest(c, frontendConfig) {
 			// Log untuk debugging (opsional)
 			// fmt.Println("Frontend request detected, bypassing access key validation")
+			if options.csrf != nil && !options.csrf.check(c) {
+				return
+			}
 			c.Next()
 			return
 		}
@@ -46,49 +76,115 @@ func AccessKeyMiddleware(secretKey string, expireSeconds int64, frontendConfig *
 		// Ambil Access-Key dari header
 		accessKey := c.GetHeader("Access-Key")
 		if accessKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Tidak ada otorisasi service"})
-			c.Abort()
+			abortWithTranslation(c, http.StatusUnauthorized, "service.unauthorized")
 			return
 		}
 
-		security := helpers.NewSecurityAccessKey()
-		decryptedKey, err := security.Decrypt(accessKey)
+		verified, err := options.verifyAccessKey(c.Request.Header, accessKey, secretKey, expireSeconds)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Tidak ada otorisasi service"})
-			c.Abort()
+			log.Printf("access key: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			abortWithTranslation(c, http.StatusUnauthorized, "service.unauthorized")
 			return
 		}
+		claims, secret := verified.claims, verified.secret
+		c.Set(CallerContextKey, verified.caller)
+		if verified.keyID != "" {
+			c.Set(AccessKeyIDContextKey, verified.keyID)
+		}
 
-		// Pisahkan secretKey dan timestamp
-		parts := strings.Split(decryptedKey, "@")
-		if len(parts) != 2 || parts[0] != secretKey {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Tidak ada otorisasi service"})
-			c.Abort()
-			return
+		if options.v2 != nil {
+			if status, reason, err := options.v2.check(claims, verified.caller); err != nil {
+				log.Printf("access key: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+				abortWithReason(c, status, reason)
+				return
+			}
 		}
 
-		// Validasi waktu dengan mengonversi timestamp string ke int64
-		requestTimestamp, err := strconv.ParseInt(parts[1], 10, 64)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Tidak ada otorisasi service"})
-			c.Abort()
-			return
+		if options.signature != nil {
+			if err := verifyRequestSignature(c, secret, options.signature); err != nil {
+				log.Printf("access key: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+				abortWithTranslation(c, http.StatusUnauthorized, "service.unauthorized")
+				return
+			}
+		}
+
+		// The nonce is recorded only once everything else checked out, so a
+		// request with a forged signature cannot burn the genuine request's key.
+		if options.replay != nil {
+			if reason, err := options.replay.check(c.Request.Context(), claims, expireSeconds); err != nil {
+				log.Printf("access key: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+				abortWithReason(c, http.StatusUnauthorized, reason)
+				return
+			}
 		}
 
-		// Waktu saat ini dalam bentuk Unix timestamp
-		currentTimestamp := time.Now().Unix()
+		c.Set(AccessKeyClaimsContextKey, claims)
 
-		// Periksa apakah waktu request sudah lewat atau jika waktu sekarang terlalu jauh dari waktu request
-		if requestTimestamp > currentTimestamp || currentTimestamp-requestTimestamp > expireSeconds {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Tidak ada otorisasi service"})
-			c.Abort()
+		if options.routes != nil && !checkRoutePolicy(c, options.routes) {
 			return
 		}
+
 		// Lanjut ke handler berikutnya
 		c.Next()
 	}
 }
 
+// verifiedAccessKey is an Access-Key that passed verifyAccessKey.
+type verifiedAccessKey struct {
+	claims *helpers.AccessKeyClaims
+	// secret is the key the Access-Key was issued with.
+	secret string
+	caller *CallerIdentity
+	// keyID is the keyring key that matched, if a keyring is used.
+	keyID string
+}
+
+// verifyAccessKey decrypts and checks accessKey and identifies the caller.
+// Callers naming themselves in Access-Caller are checked against their own
+// credential; the others against the shared key, or with a keyring every
+// accepted key, the Access-Key-Id hint first.
+func (o *accessKeyOptions) verifyAccessKey(header http.Header, accessKey, secretKey string, expireSeconds int64) (*verifiedAccessKey, error) {
+	if o.callers != nil {
+		if callerID := header.Get(helpers.CallerHeader); callerID != "" {
+			credential, ok := o.callers.credentials.Lookup(callerID)
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown caller %q", helpers.ErrAccessKeyInvalid, callerID)
+			}
+			claims, err := o.security.WithKey(credential.Key).Verify(accessKey, credential.Key, expireSeconds)
+			if err != nil {
+				return nil, fmt.Errorf("caller %q: %w", callerID, err)
+			}
+			if err := checkCallerClaims(credential, claims); err != nil {
+				return nil, fmt.Errorf("caller %q: %w", callerID, err)
+			}
+			caller := &CallerIdentity{ID: credential.ID, Name: credential.Name, Scopes: credential.Scopes}
+			return &verifiedAccessKey{claims: claims, secret: credential.Key, caller: caller}, nil
+		}
+		if o.callers.required {
+			return nil, fmt.Errorf("%w: missing %s header", helpers.ErrAccessKeyInvalid, helpers.CallerHeader)
+		}
+	}
+
+	if o.keyring == nil {
+		claims, err := o.security.Verify(accessKey, secretKey, expireSeconds)
+		if err != nil {
+			return nil, err
+		}
+		return &verifiedAccessKey{claims: claims, secret: secretKey, caller: &CallerIdentity{Shared: true}}, nil
+	}
+
+	err := helpers.ErrAccessKeyInvalid
+	for _, key := range o.keyring.Candidates(header.Get(helpers.KeyIDHeader)) {
+		var claims *helpers.AccessKeyClaims
+		claims, err = o.security.WithKey(key.Secret).Verify(accessKey, key.Secret, expireSeconds)
+		if err == nil {
+			o.keyring.Record(key.ID)
+			return &verifiedAccessKey{claims: claims, secret: key.Secret, caller: &CallerIdentity{Shared: true}, keyID: key.ID}, nil
+		}
+	}
+	return nil, err
+}
+
 func isFrontendRequest(c *gin.Context, config *FrontendConfig) bool {
 	// CHECK 1: Custom header (highest priority)
 	if config.CustomHeader != "" {
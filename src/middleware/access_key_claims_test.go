@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+const accessKeyTestCallerKey = "base64:Y2FsbGVyLWtleS0wMTIzNDU2Nzg5YWJjZGVmMDEyMzQ="
+
+// accessKeyV2Test builds a v2 Access-Key for secret; edit adjusts the claims.
+func accessKeyV2Test(t *testing.T, secret string, edit func(*helpers.AccessKeyClaims)) string {
+	t.Helper()
+	claims, err := helpers.NewAccessKeyClaims("students")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edit != nil {
+		edit(&claims)
+	}
+	key, err := helpers.EncryptAccessKeyClaims(secret, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestAccessKeyV2Claims(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("APP_KEY", laravelTestKey)
+	credentials, err := helpers.NewCallerCredentials(helpers.CallerCredential{ID: "registration", Key: accessKeyTestCallerKey, Scopes: []string{"students.read", "students.write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1Key, err := helpers.NewSecurityAccessKeyWithKey(laravelTestKey).GenerateNonceAccessKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := func(edit func(*helpers.AccessKeyClaims)) string { return accessKeyV2Test(t, laravelTestKey, edit) }
+	caller := func(edit func(*helpers.AccessKeyClaims)) string {
+		return accessKeyV2Test(t, accessKeyTestCallerKey, edit)
+	}
+	withScopes := func(scopes ...string) func(*helpers.AccessKeyClaims) {
+		return func(c *helpers.AccessKeyClaims) { c.Issuer, c.Scopes = "registration", scopes }
+	}
+
+	tests := []struct {
+		name     string
+		config   AccessKeyV2Config
+		key      string
+		callerID string
+		status   int
+		reason   string
+	}{
+		{"audience", AccessKeyV2Config{Audience: "students"}, shared(nil), "", http.StatusOK, ""},
+		{"wrong audience", AccessKeyV2Config{Audience: "grades"}, shared(nil), "", http.StatusUnauthorized, ReasonAccessKeyAudience},
+		{"no audience", AccessKeyV2Config{Audience: "students"}, shared(func(c *helpers.AccessKeyClaims) { c.Audience = "" }), "", http.StatusUnauthorized, ReasonAccessKeyAudience},
+		{"expired", AccessKeyV2Config{}, shared(func(c *helpers.AccessKeyClaims) { c.ExpiresAt = c.Timestamp - 1 }), "", http.StatusUnauthorized, ""},
+		{"v1 refused", AccessKeyV2Config{}, v1Key, "", http.StatusUnauthorized, ReasonAccessKeyV1},
+		{"v1 accepted", AccessKeyV2Config{AcceptV1: true}, v1Key, "", http.StatusOK, ""},
+		{"v1 transition over", AccessKeyV2Config{AcceptV1: true, AcceptV1Until: time.Now().Add(-time.Minute)}, v1Key, "", http.StatusUnauthorized, ReasonAccessKeyV1},
+		{"caller scope", AccessKeyV2Config{Scopes: []string{"students.write"}}, caller(withScopes("students.write")), "registration", http.StatusOK, ""},
+		{"caller lacks scope", AccessKeyV2Config{Scopes: []string{"students.write"}}, caller(withScopes("students.read")), "registration", http.StatusForbidden, ReasonAccessKeyScope},
+		{"caller claims ungranted scope", AccessKeyV2Config{}, caller(withScopes("grades.write")), "registration", http.StatusUnauthorized, ""},
+		{"caller wrong issuer", AccessKeyV2Config{}, caller(func(c *helpers.AccessKeyClaims) { c.Issuer = "grades" }), "registration", http.StatusUnauthorized, ""},
+		// Anyone holding APP_KEY can mint any scope, so shared keys grant none.
+		{"shared key claiming scope", AccessKeyV2Config{Scopes: []string{"students.write"}}, shared(withScopes("students.write")), "", http.StatusForbidden, ReasonAccessKeyScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", AccessKeyMiddleware(laravelTestKey, 60, nil, WithCallerCredentials(credentials, false), WithAccessKeyV2(tt.config)), func(c *gin.Context) {
+				c.String(http.StatusOK, "ok")
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Access-Key", tt.key)
+			if tt.callerID != "" {
+				req.Header.Set(helpers.CallerHeader, tt.callerID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.reason != "" && !strings.Contains(w.Body.String(), `"reason":"`+tt.reason+`"`) {
+				t.Fatalf("body = %s, want reason %s", w.Body.String(), tt.reason)
+			}
+		})
+	}
+}
+
+func TestCallerScopesIgnoreSharedKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("APP_KEY", laravelTestKey)
+	credentials, err := helpers.NewCallerCredentials(helpers.CallerCredential{ID: "registration", Key: accessKeyTestCallerKey, Scopes: []string{"students.read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/", AccessKeyMiddleware(laravelTestKey, 60, nil, WithCallerCredentials(credentials, false)), func(c *gin.Context) {
+		c.JSON(http.StatusOK, callerScopes(c))
+	})
+	scopes := func(key, callerID string) []string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Access-Key", key)
+		if callerID != "" {
+			req.Header.Set(helpers.CallerHeader, callerID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+		var scopes []string
+		if err := json.Unmarshal(w.Body.Bytes(), &scopes); err != nil {
+			t.Fatal(err)
+		}
+		return scopes
+	}
+
+	if got := scopes(accessKeyV2Test(t, laravelTestKey, func(c *helpers.AccessKeyClaims) { c.Scopes = []string{"students.write", "admin"} }), ""); len(got) != 0 {
+		t.Fatalf("shared key scopes = %v, want none", got)
+	}
+	if got := scopes(accessKeyV2Test(t, accessKeyTestCallerKey, func(c *helpers.AccessKeyClaims) { c.Issuer = "registration" }), "registration"); len(got) != 1 || got[0] != "students.read" {
+		t.Fatalf("caller scopes = %v, want the credential's", got)
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/SIM-MBKM/mod-service/src/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Requirement decides whether a user principal may continue.
+// When it fails, reason explains why for the access log.
+type Requirement func(principal *UserPrincipal) (ok bool, reason string)
+
+// RoleResolver loads roles and permissions for principals whose token did not carry them.
+type RoleResolver interface {
+	ResolveRoles(ctx context.Context, principal *UserPrincipal) (roles []string, permissions []string, err error)
+}
+
+// AuthServiceRoleResolver looks roles and permissions up through AuthService.
+type AuthServiceRoleResolver struct {
+	Auth *service.AuthService
+}
+
+// ResolveRoles implements RoleResolver.
+func (r *AuthServiceRoleResolver) ResolveRoles(ctx context.Context, principal *UserPrincipal) ([]string, []string, error) {
+	user, err := r.Auth.UserWithContext(ctx, principal.Token)
+	if err != nil {
+		return nil, nil, err
+	}
+	return toStringSlice(user["roles"]), toStringSlice(user["permissions"]), nil
+}
+
+// Authorizer builds route guards. The zero value only uses the roles and
+// permissions already present on the principal.
+type Authorizer struct {
+	Resolver RoleResolver
+}
+
+// NewAuthorizer creates a new instance of Authorizer.
+func NewAuthorizer(resolver RoleResolver) *Authorizer {
+	return &Authorizer{Resolver: resolver}
+}
+
+var defaultAuthorizer = &Authorizer{}
+
+// rolesResolvedContextKey marks requests whose principal already went through a RoleResolver.
+const rolesResolvedContextKey = "mod-service.roles_resolved"
+
+// Require aborts with 403 unless every requirement passes, or with 503 when
+// the Resolver fails.
+func (a *Authorizer) Require(requirements ...Requirement) gin.HandlerFunc {
+	requirement := AllOf(requirements...)
+
+	return func(c *gin.Context) {
+		principal, ok := GetUser(c)
+		if !ok {
+			log.Printf("authorization denied: %s %s: no authenticated user", c.Request.Method, c.Request.URL.Path)
+			abortWithTranslation(c, http.StatusUnauthorized, "auth.unauthenticated")
+			return
+		}
+
+		if a.Resolver != nil && len(principal.Roles) == 0 && len(principal.Permissions) == 0 && !c.GetBool(rolesResolvedContextKey) {
+			roles, permissions, err := a.Resolver.ResolveRoles(c.Request.Context(), principal)
+			if err != nil {
+				log.Printf("authorization: resolving roles for user %s failed: %v", principal.ID, err)
+				abortWithTranslation(c, http.StatusServiceUnavailable, "auth.unavailable")
+				return
+			}
+			// Stacked guards reuse the resolved roles, even when there are none.
+			resolved := *principal
+			resolved.Roles, resolved.Permissions = roles, permissions
+			principal = &resolved
+			c.Set(UserContextKey, principal)
+			c.Set(rolesResolvedContextKey, true)
+		}
+
+		if ok, reason := requirement(principal); !ok {
+			log.Printf("authorization denied: %s %s: user %s: %s", c.Request.Method, c.Request.URL.Path, principal.ID, reason)
+			abortWithTranslation(c, http.StatusForbidden, "auth.forbidden")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireRole allows users having any of the given roles.
+func (a *Authorizer) RequireRole(roles ...string) gin.HandlerFunc {
+	return a.Require(Role(roles...))
+}
+
+// RequirePermission allows users having any of the given permissions.
+func (a *Authorizer) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return a.Require(Permission(permissions...))
+}
+
+// Require aborts with 403 unless every requirement passes, using roles from the principal only.
+func Require(requirements ...Requirement) gin.HandlerFunc {
+	return defaultAuthorizer.Require(requirements...)
+}
+
+// RequireRole allows users having any of the given roles.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return defaultAuthorizer.RequireRole(roles...)
+}
+
+// RequirePermission allows users having any of the given permissions.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return defaultAuthorizer.RequirePermission(permissions...)
+}
+
+// Role passes when the principal has any of the given roles.
+func Role(roles ...string) Requirement {
+	return func(principal *UserPrincipal) (bool, string) {
+		for _, role := range roles {
+			if principal.HasRole(role) {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("missing role %s", strings.Join(roles, "|"))
+	}
+}
+
+// Permission passes when the principal has any of the given permissions.
+func Permission(permissions ...string) Requirement {
+	return func(principal *UserPrincipal) (bool, string) {
+		for _, permission := range permissions {
+			if principal.HasPermission(permission) {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("missing permission %s", strings.Join(permissions, "|"))
+	}
+}
+
+// AnyOf passes when at least one requirement passes.
+func AnyOf(requirements ...Requirement) Requirement {
+	return func(principal *UserPrincipal) (bool, string) {
+		reasons := make([]string, 0, len(requirements))
+		for _, requirement := range requirements {
+			ok, reason := requirement(principal)
+			if ok {
+				return true, ""
+			}
+			reasons = append(reasons, reason)
+		}
+		return false, strings.Join(reasons, " or ")
+	}
+}
+
+// AllOf passes when every requirement passes.
+func AllOf(requirements ...Requirement) Requirement {
+	return func(principal *UserPrincipal) (bool, string) {
+		for _, requirement := range requirements {
+			if ok, reason := requirement(principal); !ok {
+				return false, reason
+			}
+		}
+		return true, ""
+	}
+}
+
+// HasRole reports whether the principal has the given role.
+func (p *UserPrincipal) HasRole(role string) bool {
+	return containsString(p.Roles, role)
+}
+
+// HasPermission reports whether the principal has the given permission.
+func (p *UserPrincipal) HasPermission(permission string) bool {
+	return containsString(p.Permissions, permission)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubRoleResolver struct {
+	roles, permissions []string
+	err                error
+	calls              int
+}
+
+func (r *stubRoleResolver) ResolveRoles(ctx context.Context, principal *UserPrincipal) ([]string, []string, error) {
+	r.calls++
+	return r.roles, r.permissions, r.err
+}
+
+// serveAuthorized runs guards for principal, nil meaning no authenticated user.
+func serveAuthorized(principal *UserPrincipal, guards ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers := []gin.HandlerFunc{func(c *gin.Context) {
+		if principal != nil {
+			c.Set(UserContextKey, principal)
+		}
+	}}
+	handlers = append(handlers, guards...)
+	handlers = append(handlers, func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/", handlers...)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestRequireRolesAndPermissions(t *testing.T) {
+	admin := &UserPrincipal{ID: "1", Roles: []string{"admin"}, Permissions: []string{"grades.read"}}
+	student := &UserPrincipal{ID: "2", Roles: []string{"student"}}
+
+	tests := []struct {
+		name      string
+		principal *UserPrincipal
+		guard     gin.HandlerFunc
+		want      int
+	}{
+		{"role", admin, RequireRole("admin"), http.StatusOK},
+		{"any role", student, RequireRole("admin", "student"), http.StatusOK},
+		{"missing role", student, RequireRole("admin"), http.StatusForbidden},
+		{"permission", admin, RequirePermission("grades.read"), http.StatusOK},
+		{"missing permission", student, RequirePermission("grades.read"), http.StatusForbidden},
+		{"all of", admin, Require(Role("admin"), Permission("grades.read")), http.StatusOK},
+		{"all of fails", admin, Require(Role("admin"), Permission("grades.write")), http.StatusForbidden},
+		{"any of", student, Require(AnyOf(Role("admin"), Role("student"))), http.StatusOK},
+		{"any of fails", student, Require(AnyOf(Role("admin"), Permission("grades.read"))), http.StatusForbidden},
+		{"no user", nil, RequireRole("admin"), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serveAuthorized(tt.principal, tt.guard); w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthorizerResolver(t *testing.T) {
+	resolver := &stubRoleResolver{roles: []string{"lecturer"}}
+	authorizer := NewAuthorizer(resolver)
+
+	// Stacked guards resolve the roles once.
+	w := serveAuthorized(&UserPrincipal{ID: "1"}, authorizer.RequireRole("lecturer"), authorizer.RequireRole("lecturer", "admin"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if resolver.calls != 1 {
+		t.Fatalf("resolver called %d times, want 1", resolver.calls)
+	}
+
+	// Roles already on the principal are used as they are.
+	resolver.calls = 0
+	if w := serveAuthorized(&UserPrincipal{ID: "1", Roles: []string{"student"}}, authorizer.RequireRole("lecturer")); w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if resolver.calls != 0 {
+		t.Fatalf("resolver called %d times, want 0", resolver.calls)
+	}
+}
+
+func TestAuthorizerResolverFailure(t *testing.T) {
+	authorizer := NewAuthorizer(&stubRoleResolver{err: errors.New("auth service down")})
+	w := serveAuthorized(&UserPrincipal{ID: "1"}, authorizer.RequireRole("admin"))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
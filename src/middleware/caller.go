@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+// CallerContextKey is the gin context key holding the *CallerIdentity of the calling service.
+const CallerContextKey = "mod-service.caller"
+
+// CallerIdentity is the service that made the request.
+type CallerIdentity struct {
+	// ID is empty for callers authenticated with the shared APP_KEY.
+	ID     string
+	Name   string
+	Scopes []string
+	// Shared is true when the caller used the shared APP_KEY, so its identity is unknown.
+	Shared bool
+}
+
+type callerCredentials struct {
+	credentials *helpers.CallerCredentials
+	required    bool
+}
+
+// WithCallerCredentials verifies Access-Keys of callers sending an Access-Caller
+// header with their own key from credentials. When required is false, callers
+// without the header still authenticate with the shared APP_KEY during migration.
+func WithCallerCredentials(credentials *helpers.CallerCredentials, required bool) AccessKeyOption {
+	return func(options *accessKeyOptions) {
+		options.callers = &callerCredentials{credentials: credentials, required: required}
+	}
+}
+
+// GetCaller returns the calling service identified by AccessKeyMiddleware.
+func GetCaller(c *gin.Context) (*CallerIdentity, bool) {
+	value, exists := c.Get(CallerContextKey)
+	if !exists {
+		return nil, false
+	}
+	caller, ok := value.(*CallerIdentity)
+	return caller, ok && caller != nil
+}
+
+// checkCallerClaims ensures a v2 key names the caller as issuer and claims no
+// scope beyond those of its credential.
+func checkCallerClaims(credential helpers.CallerCredential, claims *helpers.AccessKeyClaims) error {
+	if claims.Version != helpers.AccessKeyV2 {
+		return nil
+	}
+	if claims.Issuer != "" && claims.Issuer != credential.ID {
+		return fmt.Errorf("%w: issued by %q", helpers.ErrAccessKeyInvalid, claims.Issuer)
+	}
+	for _, scope := range claims.Scopes {
+		if !containsString(credential.Scopes, scope) {
+			return fmt.Errorf("%w: scope %q not granted to caller", helpers.ErrAccessKeyInvalid, scope)
+		}
+	}
+	return nil
+}
+
+// callerScopes returns the scopes the caller holds: those of its credential.
+// A v2 Access-Key may only claim scopes its credential grants, so its scopes
+// add none; a key claiming more holds none at all. Keys made with the shared
+// APP_KEY assert their scopes themselves, anyone holding APP_KEY can mint any,
+// so shared callers hold none either.
+func callerScopes(c *gin.Context) []string {
+	caller, ok := GetCaller(c)
+	if !ok || caller.Shared {
+		return nil
+	}
+	if claims, ok := GetAccessKeyClaims(c); ok {
+		for _, scope := range claims.Scopes {
+			if !containsString(caller.Scopes, scope) {
+				return nil
+			}
+		}
+	}
+	return append([]string{}, caller.Scopes...)
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionIDContextKey is the gin context key holding the Laravel session ID.
+const SessionIDContextKey = "mod-service.session_id"
+
+// cookiesDecryptedKey marks requests whose cookies DecryptCookies already decrypted.
+const cookiesDecryptedKey = "mod-service.cookies_decrypted"
+
+// statusPageExpired is the status Laravel answers a CSRF token mismatch with.
+const statusPageExpired = 419
+
+var errCSRFMismatch = errors.New("CSRF token mismatch")
+
+// SessionTokenFunc returns the CSRF token ("_token") stored in a Laravel session.
+type SessionTokenFunc func(ctx context.Context, sessionID string) (string, error)
+
+// CSRFConfig configures VerifyCSRFToken and WithCSRFProtection.
+type CSRFConfig struct {
+	// Cookies decrypts Laravel cookies; nil uses helpers.NewLaravelCookiesFromEnv.
+	Cookies *helpers.LaravelCookies
+	// SessionCookie is the Laravel session cookie; empty uses helpers.LaravelSessionCookieName.
+	SessionCookie string
+	// SessionToken, when set, compares the request token with the one in the
+	// session, exactly like Laravel. Otherwise the token must match the
+	// XSRF-TOKEN cookie (double submit), which needs no access to the session store.
+	SessionToken SessionTokenFunc
+}
+
+type csrfVerifier struct {
+	config  CSRFConfig
+	cookies *helpers.LaravelCookies
+}
+
+func newCSRFVerifier(config CSRFConfig) *csrfVerifier {
+	cookies := config.Cookies
+	if cookies == nil {
+		var err error
+		if cookies, err = helpers.NewLaravelCookiesFromEnv(); err != nil {
+			// Tanpa APP_KEY yang valid semua request yang mengubah data ditolak.
+			log.Printf("csrf: %v", err)
+		}
+	}
+	if config.SessionCookie == "" {
+		config.SessionCookie = helpers.LaravelSessionCookieName()
+	}
+	return &csrfVerifier{config: config, cookies: cookies}
+}
+
+// cookie returns the decrypted value of the named Laravel cookie.
+func (v *csrfVerifier) cookie(c *gin.Context, name string) (string, bool) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	value, err := helpers.DecodeCookieValue(cookie.Value)
+	if err != nil || value == "" {
+		return "", false
+	}
+	if c.GetBool(cookiesDecryptedKey) {
+		return value, true
+	}
+	if v.cookies == nil {
+		return "", false
+	}
+	decrypted, err := v.cookies.Decrypt(name, value)
+	if err != nil {
+		return "", false
+	}
+	return decrypted, true
+}
+
+// loadSession stores the session ID, if the request carries a valid session cookie.
+func (v *csrfVerifier) loadSession(c *gin.Context) {
+	if sessionID, ok := v.cookie(c, v.config.SessionCookie); ok {
+		c.Set(SessionIDContextKey, sessionID)
+	}
+}
+
+// verify checks the token of a state changing request, like VerifyCsrfToken.
+func (v *csrfVerifier) verify(c *gin.Context) error {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	token := c.PostForm(helpers.CSRFInput)
+	if token == "" {
+		token = c.GetHeader(helpers.CSRFHeader)
+	}
+	if header := c.GetHeader(helpers.XSRFHeader); token == "" && header != "" && v.cookies != nil {
+		token, _ = v.cookies.DecryptXSRFHeader(header)
+	}
+
+	var expected string
+	if v.config.SessionToken != nil {
+		sessionID := GetSessionID(c)
+		if sessionID == "" {
+			return errCSRFMismatch
+		}
+		sessionToken, err := v.config.SessionToken(c.Request.Context(), sessionID)
+		if err != nil {
+			return err
+		}
+		expected = sessionToken
+	} else {
+		expected, _ = v.cookie(c, helpers.XSRFCookieName)
+	}
+
+	if token == "" || expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return errCSRFMismatch
+	}
+	return nil
+}
+
+// check loads the session and verifies the token, aborting the request on a mismatch.
+func (v *csrfVerifier) check(c *gin.Context) bool {
+	v.loadSession(c)
+	if err := v.verify(c); err != nil {
+		log.Printf("csrf: %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		abortWithTranslation(c, statusPageExpired, "session.csrf_mismatch")
+		return false
+	}
+	return true
+}
+
+// VerifyCSRFToken enforces Laravel's CSRF protection on POST, PUT, PATCH and
+// DELETE requests: the "_token" input, X-CSRF-TOKEN header or encrypted
+// X-XSRF-TOKEN header must carry the session's token. Mismatches get 419
+// "CSRF token mismatch.". The Laravel session ID is available through GetSessionID.
+func VerifyCSRFToken(config CSRFConfig) gin.HandlerFunc {
+	verifier := newCSRFVerifier(config)
+	return func(c *gin.Context) {
+		if !verifier.check(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// WithCSRFProtection makes AccessKeyMiddleware require a valid CSRF token from
+// requests it lets through as frontend requests, instead of trusting the
+// FrontendConfig heuristics alone.
+func WithCSRFProtection(config CSRFConfig) AccessKeyOption {
+	return func(options *accessKeyOptions) {
+		options.csrf = newCSRFVerifier(config)
+	}
+}
+
+// DecryptCookies replaces the request's Laravel encrypted cookies with their
+// plaintext, like EncryptCookies; cookies that fail to decrypt are dropped.
+// Cookies named in except are passed through unchanged. Values stay URL
+// encoded as browsers send them, c.Cookie decodes them.
+func DecryptCookies(cookies *helpers.LaravelCookies, except ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plain := make([]*http.Cookie, 0)
+		for _, cookie := range c.Request.Cookies() {
+			if containsString(except, cookie.Name) {
+				plain = append(plain, cookie)
+				continue
+			}
+			encrypted, err := helpers.DecodeCookieValue(cookie.Value)
+			if err != nil {
+				continue
+			}
+			if value, err := cookies.Decrypt(cookie.Name, encrypted); err == nil {
+				plain = append(plain, &http.Cookie{Name: cookie.Name, Value: helpers.EncodeCookieValue(value)})
+			}
+		}
+
+		c.Request.Header.Del("Cookie")
+		for _, cookie := range plain {
+			c.Request.AddCookie(cookie)
+		}
+		c.Set(cookiesDecryptedKey, true)
+		c.Next()
+	}
+}
+
+// SetEncryptedCookie sets cookie on the response, encrypted and URL encoded
+// so Laravel can read it.
+func SetEncryptedCookie(c *gin.Context, cookies *helpers.LaravelCookies, cookie *http.Cookie) error {
+	encrypted, err := cookies.Encrypt(cookie.Name, cookie.Value)
+	if err != nil {
+		return err
+	}
+	encryptedCookie := *cookie
+	encryptedCookie.Value = helpers.EncodeCookieValue(encrypted)
+	http.SetCookie(c.Writer, &encryptedCookie)
+	return nil
+}
+
+// GetSessionID returns the Laravel session ID of the request, or "".
+func GetSessionID(c *gin.Context) string {
+	return c.GetString(SessionIDContextKey)
+}
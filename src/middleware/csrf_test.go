@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	laravelTestKey       = "base64:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	laravelTestSessionID = "Zq3bW1mHkP8sT2vX9yL4nR6cJ0aE5dF7gU1iO3pQ"
+	// laravelTestSessionCookie is a laravel_session cookie as EncryptCookies
+	// writes it for laravelTestSessionID with laravelTestKey, before URL encoding.
+	laravelTestSessionCookie = "eyJpdiI6ImYwczN6RlBBQ0JJTEtidVB3Y0o4ZFE9PSIsInZhbHVlIjoiWit6RjFJMjQydU1FYUpWbHlGZkE3TDdOWVdvK1J5VTdXakFzNnBqbVJLMTlFRnFkdG03enh0cjlnMnNpSEVpYVRtaklGeDl1dE0rclNpVFdLN1hzVUYwUDdSdmpGTThEQi9CQnNpUnROdzZPNEl2OGg0V3dUeEJ6Q1pWd1V6TkMiLCJtYWMiOiI1Zjk1YTI4NGFmZWMyNDZlMzJjYWZlZTg3NGI0NWY3MzdjNTliNzlhMjgzZTdlNzU2ZjZmNjcyMWUzYTliZjFmIiwidGFnIjoiIn0="
+)
+
+func newTestLaravelCookies(t *testing.T) *helpers.LaravelCookies {
+	t.Helper()
+	encrypter, err := helpers.NewLaravelEncrypter(laravelTestKey, helpers.LaravelAES256CBC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return helpers.NewLaravelCookies(encrypter)
+}
+
+func TestDecryptCookiesURLEncoded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cookies := newTestLaravelCookies(t)
+
+	router := gin.New()
+	router.GET("/", DecryptCookies(cookies), func(c *gin.Context) {
+		value, _ := c.Cookie("laravel_session")
+		c.String(http.StatusOK, value)
+	})
+
+	for name, value := range map[string]string{
+		// Laravel sends the cookie rawurlencoded, "=" becomes %3D.
+		"encoded": helpers.EncodeCookieValue(laravelTestSessionCookie),
+		"raw":     laravelTestSessionCookie,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Cookie", "laravel_session="+value)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Body.String() != laravelTestSessionID {
+				t.Fatalf("session = %q, want %q", w.Body.String(), laravelTestSessionID)
+			}
+		})
+	}
+}
+
+func TestSetEncryptedCookieRoundTrip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cookies := newTestLaravelCookies(t)
+
+	router := gin.New()
+	router.GET("/set", func(c *gin.Context) {
+		if err := SetEncryptedCookie(c, cookies, &http.Cookie{Name: "laravel_session", Value: laravelTestSessionID}); err != nil {
+			t.Error(err)
+		}
+	})
+	router.GET("/get", DecryptCookies(cookies), func(c *gin.Context) {
+		value, _ := c.Cookie("laravel_session")
+		c.String(http.StatusOK, value)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/set", nil))
+	setCookie := w.Result().Cookies()
+	if len(setCookie) != 1 {
+		t.Fatalf("Set-Cookie = %v", w.Header().Values("Set-Cookie"))
+	}
+	value := setCookie[0].Value
+	if strings.ContainsAny(value, "+/=") {
+		t.Fatalf("cookie value %q is not URL encoded", value)
+	}
+
+	// PHP fills $_COOKIE with urldecode, which turns "+" into a space.
+	phpValue, err := url.QueryUnescape(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err := cookies.Decrypt("laravel_session", phpValue); err != nil || decrypted != laravelTestSessionID {
+		t.Fatalf("Laravel would read %q, %v", decrypted, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/get", nil)
+	req.AddCookie(setCookie[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Body.String() != laravelTestSessionID {
+		t.Fatalf("session = %q, want %q", w.Body.String(), laravelTestSessionID)
+	}
+}
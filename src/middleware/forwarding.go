@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+// ForwardUserToken captures the inbound bearer token into the request context so
+// Service.RequestWithContext(c.Request.Context(), ...) forwards it on every outbound call.
+// onBehalfOf, when not nil, supplies the identity sent as the "On-Behalf-Of" header.
+func ForwardUserToken(onBehalfOf func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if token := BearerToken(c); token != "" {
+			ctx = helpers.WithUserToken(ctx, token)
+		}
+		if onBehalfOf != nil {
+			if identity := onBehalfOf(c); identity != "" {
+				ctx = helpers.WithOnBehalfOf(ctx, identity)
+			}
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// UserIDOnBehalfOf uses the ID of the principal set by UserAuthMiddleware as the
+// on-behalf-of identity. Install it after UserAuthMiddleware.
+func UserIDOnBehalfOf(c *gin.Context) string {
+	if principal, ok := GetUser(c); ok {
+		return principal.ID
+	}
+	return ""
+}
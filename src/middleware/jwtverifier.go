@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+)
+
+// JWTTokenVerifier validates bearer tokens locally instead of calling AuthService.
+type JWTTokenVerifier struct {
+	Verifier *helpers.JWTVerifier
+}
+
+// NewJWTTokenVerifier creates a TokenVerifier backed by a local JWT verifier.
+func NewJWTTokenVerifier(verifier *helpers.JWTVerifier) *JWTTokenVerifier {
+	return &JWTTokenVerifier{Verifier: verifier}
+}
+
+// VerifyToken implements TokenVerifier.
+func (v *JWTTokenVerifier) VerifyToken(ctx context.Context, token string) (*UserPrincipal, error) {
+	claims, err := v.Verifier.Verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	principal := PrincipalFromMap(claims)
+	if principal.ID == "" {
+		principal.ID = claims.Subject()
+	}
+	// OAuth style "scope" claims count as permissions when no explicit list is present.
+	if len(principal.Permissions) == 0 {
+		if scope, ok := claims["scope"].(string); ok {
+			principal.Permissions = strings.Fields(scope)
+		}
+	}
+	principal.Token = token
+	return principal, nil
+}
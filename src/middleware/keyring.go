@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+// AccessKeyIDContextKey is the gin context key holding the ID of the keyring key
+// that validated the request's Access-Key.
+const AccessKeyIDContextKey = "mod-service.access_key_id"
+
+// WithKeyring accepts Access-Keys encrypted with any key of ring that is not retired,
+// instead of only the secretKey passed to AccessKeyMiddleware.
+func WithKeyring(ring *helpers.Keyring) AccessKeyOption {
+	return func(options *accessKeyOptions) {
+		options.keyring = ring
+	}
+}
+
+// KeyringUsageHandler reports how often callers used each key, to tell when a
+// previous key can be retired. Mount it on an internal route.
+func KeyringUsageHandler(ring *helpers.Keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"keys": ring.Usage()})
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	keyringOldKey     = "base64:ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+	keyringRetiredKey = "base64:b2xkZXIta2V5LTAxMjM0NTY3ODlhYmNkZWYwMTIzNDU="
+	keyringUnknownKey = "base64:dW5rbm93bi1rZXktMDEyMzQ1Njc4OWFiY2RlZjAxMjM="
+)
+
+func TestAccessKeyMiddlewareKeyring(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("APP_KEY", laravelTestKey)
+	t.Setenv("APP_PREVIOUS_KEYS", keyringOldKey+","+keyringRetiredKey)
+	t.Setenv("APP_PREVIOUS_KEYS_RETIRE_AT", ","+time.Now().Add(-time.Minute).Format(time.RFC3339))
+	ring, err := helpers.NewKeyringFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/keys", KeyringUsageHandler(ring))
+	router.GET("/students", AccessKeyMiddleware(laravelTestKey, 60, nil, WithKeyring(ring)), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(AccessKeyIDContextKey))
+	})
+
+	tests := []struct {
+		name   string
+		secret string
+		hint   string
+		status int
+	}{
+		{"active key", laravelTestKey, "", http.StatusOK},
+		{"previous key", keyringOldKey, "", http.StatusOK},
+		{"previous key hinted", keyringOldKey, helpers.KeyID(keyringOldKey), http.StatusOK},
+		{"wrong hint falls back", keyringOldKey, helpers.KeyID(laravelTestKey), http.StatusOK},
+		{"retired key", keyringRetiredKey, "", http.StatusUnauthorized},
+		{"retired key hinted", keyringRetiredKey, helpers.KeyID(keyringRetiredKey), http.StatusUnauthorized},
+		{"unknown key", keyringUnknownKey, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessKey, err := helpers.NewSecurityAccessKeyWithKey(tt.secret).GenerateAccessKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/students", nil)
+			req.Header.Set("Access-Key", accessKey)
+			if tt.hint != "" {
+				req.Header.Set(helpers.KeyIDHeader, tt.hint)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code == http.StatusOK && w.Body.String() != helpers.KeyID(tt.secret) {
+				t.Fatalf("key id = %q, want %q", w.Body.String(), helpers.KeyID(tt.secret))
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/keys", nil))
+	var body struct {
+		Keys []helpers.KeyUsage `json:"keys"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	uses := map[string]uint64{}
+	for _, key := range body.Keys {
+		uses[key.ID] = key.Uses
+	}
+	want := map[string]uint64{helpers.KeyID(laravelTestKey): 1, helpers.KeyID(keyringOldKey): 3, helpers.KeyID(keyringRetiredKey): 0}
+	if len(uses) != len(want) {
+		t.Fatalf("usage = %v, want %v", uses, want)
+	}
+	for id, count := range want {
+		if uses[id] != count {
+			t.Fatalf("usage = %v, want %v", uses, want)
+		}
+	}
+}
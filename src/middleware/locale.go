@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleContextKey is the gin context key holding the negotiated locale.
+const LocaleContextKey = "mod-service.locale"
+
+// LocaleConfig holds the locales a service can answer in.
+type LocaleConfig struct {
+	// Supported locales, e.g. []string{"id", "en"}.
+	Supported []string
+	// Fallback is tried in order when the client asks for nothing supported.
+	// The Locale singleton default is used after it, then Supported[0].
+	Fallback []string
+}
+
+// LocaleMiddleware negotiates the request locale from the "App-Locale" header,
+// then "Accept-Language", and stores it per request in the gin context and in
+// the request context used by outbound Service calls. A nil config supports
+// no locales, so the process wide default applies.
+func LocaleMiddleware(config *LocaleConfig) gin.HandlerFunc {
+	if config == nil {
+		config = &LocaleConfig{}
+	}
+	return func(c *gin.Context) {
+		candidates := []string{c.GetHeader("App-Locale")}
+		candidates = append(candidates, helpers.ParseAcceptLanguage(c.GetHeader("Accept-Language"))...)
+
+		fallback := append(append([]string{}, config.Fallback...), helpers.GetInstance().GetLocale())
+		locale := helpers.NegotiateLocale(candidates, config.Supported, fallback)
+		if locale == "" && len(config.Supported) > 0 {
+			locale = config.Supported[0]
+		}
+
+		c.Set(LocaleContextKey, locale)
+		c.Request = c.Request.WithContext(helpers.WithLocale(c.Request.Context(), locale))
+		c.Next()
+	}
+}
+
+// GetLocale returns the locale negotiated by LocaleMiddleware, or the
+// process wide default when the middleware did not run.
+func GetLocale(c *gin.Context) string {
+	if locale := c.GetString(LocaleContextKey); locale != "" {
+		return locale
+	}
+	return helpers.GetInstance().GetLocale()
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+)
+
+// MessageVerifier checks the Access-Key in the headers of broker messages the
+// way AccessKeyMiddleware checks requests: with caller credentials, a keyring
+// or secretKey, then WithAccessKeyV2 and WithReplayProtection. Options that
+// need the HTTP request (signatures, CSRF and route policies) do not apply.
+// Use it as broker.Consumer's Verify.
+func MessageVerifier(secretKey string, expireSeconds int64, opts ...AccessKeyOption) (func(ctx context.Context, header http.Header) error, error) {
+	security, err := helpers.NewSecurityAccessKeyFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("middleware: invalid access key configuration: %w", err)
+	}
+	options := &accessKeyOptions{security: security}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return func(ctx context.Context, header http.Header) error {
+		accessKey := header.Get("Access-Key")
+		if accessKey == "" {
+			return errors.New("missing Access-Key")
+		}
+		verified, err := options.verifyAccessKey(header, accessKey, secretKey, expireSeconds)
+		if err != nil {
+			return err
+		}
+		if options.v2 != nil {
+			if _, _, err := options.v2.check(verified.claims, verified.caller); err != nil {
+				return err
+			}
+		}
+		if options.replay != nil {
+			if _, err := options.replay.check(ctx, verified.claims, expireSeconds); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyEngineContextKey is the gin context key holding the *helpers.PolicyEngine
+// used by Can and Decide.
+const PolicyEngineContextKey = "mod-service.policy_engine"
+
+var defaultPolicyEngine atomic.Pointer[helpers.PolicyEngine]
+
+// SetPolicyEngine sets the engine Can and Decide use on requests that did not
+// pass through PolicyMiddleware. It is safe to call while requests are served.
+func SetPolicyEngine(engine *helpers.PolicyEngine) {
+	defaultPolicyEngine.Store(engine)
+}
+
+// PolicyMiddleware aborts with 403 unless engine allows action for the caller
+// and user of the request. An empty action uses the HTTP method. The engine
+// stays available to handlers through Can and Decide.
+func PolicyMiddleware(engine *helpers.PolicyEngine, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(PolicyEngineContextKey, engine)
+
+		name := action
+		if name == "" {
+			name = c.Request.Method
+		}
+		decision := engine.Decide(NewPolicyInput(c, name, nil))
+		if !decision.Allowed {
+			log.Printf("policy denied: %s %s: %s", c.Request.Method, c.Request.URL.Path, decision.Explain())
+			abortWithTranslation(c, http.StatusForbidden, "auth.forbidden")
+			return
+		}
+		c.Next()
+	}
+}
+
+// Can reports whether the caller and user of the request may perform action on
+// resource, e.g. Can(c, "grades.update", gin.H{"owner_id": grade.StudentID}).
+func Can(c *gin.Context, action string, resource map[string]interface{}) bool {
+	return Decide(c, action, resource).Allowed
+}
+
+// Decide is Can with the full decision, for handlers that explain denials.
+// Without an engine every action is denied.
+func Decide(c *gin.Context, action string, resource map[string]interface{}) helpers.PolicyDecision {
+	engine := defaultPolicyEngine.Load()
+	if value, exists := c.Get(PolicyEngineContextKey); exists {
+		// A value of another type, set by some other handler, means no engine.
+		engine, _ = value.(*helpers.PolicyEngine)
+	}
+	if engine == nil {
+		return helpers.PolicyDecision{Reason: "no policy engine configured"}
+	}
+	return engine.Decide(NewPolicyInput(c, action, resource))
+}
+
+// NewPolicyInput collects the caller, user and route parameters of the request.
+// The caller's scopes are those of callerScopes, so shared-key callers hold none.
+func NewPolicyInput(c *gin.Context, action string, resource map[string]interface{}) helpers.PolicyInput {
+	input := helpers.PolicyInput{
+		Action:   action,
+		Method:   c.Request.Method,
+		Path:     c.Request.URL.Path,
+		Route:    c.FullPath(),
+		Params:   make(map[string]string, len(c.Params)),
+		Resource: resource,
+	}
+	for _, param := range c.Params {
+		input.Params[param.Key] = param.Value
+	}
+
+	if caller, ok := GetCaller(c); ok {
+		input.Caller = &helpers.PolicyCaller{ID: caller.ID, Scopes: callerScopes(c), Shared: caller.Shared}
+	}
+	if user, ok := GetUser(c); ok {
+		input.User = &helpers.PolicyUser{
+			ID:          user.ID,
+			Roles:       user.Roles,
+			Permissions: user.Permissions,
+			Attributes:  user.Attributes,
+		}
+	}
+	return input
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+func newTestPolicy(t *testing.T) *helpers.PolicyEngine {
+	t.Helper()
+	engine, err := helpers.NewPolicyEngine(helpers.PolicyDocument{Rules: []helpers.PolicyRule{
+		{ID: "read", Effect: helpers.PolicyAllow, Actions: []string{"GET"}},
+		{ID: "own-grade", Effect: helpers.PolicyAllow, Actions: []string{"grades.update"}, When: []helpers.PolicyCondition{{Attr: "user.id", Op: "eq", Ref: "resource.student_id"}}},
+		{ID: "caller-write", Effect: helpers.PolicyAllow, Actions: []string{"PUT"}, When: []helpers.PolicyCondition{
+			{Attr: "caller.scopes", Op: "contains", Value: "grades.write"},
+			{Attr: "params.id", Op: "eq", Value: 42},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := newTestPolicy(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		caller *CallerIdentity
+		status int
+	}{
+		{"method as action", http.MethodGet, "/grades/1", nil, http.StatusOK},
+		{"caller scope and param", http.MethodPut, "/grades/42", &CallerIdentity{ID: "grades", Scopes: []string{"grades.write"}}, http.StatusOK},
+		{"other param", http.MethodPut, "/grades/43", &CallerIdentity{ID: "grades", Scopes: []string{"grades.write"}}, http.StatusForbidden},
+		// Shared-key callers hold no scopes, see callerScopes.
+		{"shared caller", http.MethodPut, "/grades/42", &CallerIdentity{Shared: true, Scopes: []string{"grades.write"}}, http.StatusForbidden},
+		{"no rule", http.MethodDelete, "/grades/42", nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.caller != nil {
+					c.Set(CallerContextKey, tt.caller)
+				}
+			})
+			router.Any("/grades/:id", PolicyMiddleware(engine, ""), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCanAndDecide(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := newTestPolicy(t)
+	t.Cleanup(func() { SetPolicyEngine(nil) })
+
+	serve := func(handlers ...gin.HandlerFunc) string {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set(UserContextKey, &UserPrincipal{ID: "5025201001"}) })
+		router.GET("/grades/:id", handlers...)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/grades/1", nil))
+		return w.Body.String()
+	}
+	check := func(c *gin.Context) {
+		own := Can(c, "grades.update", map[string]interface{}{"student_id": float64(5025201001)})
+		other := Decide(c, "grades.update", map[string]interface{}{"student_id": float64(5025201002)})
+		if own && !other.Allowed {
+			c.String(http.StatusOK, "own only")
+			return
+		}
+		c.String(http.StatusOK, "%v %v %s", own, other.Allowed, other.Reason)
+	}
+
+	// Without an engine everything is denied.
+	if got := serve(check); got != "false false no policy engine configured" {
+		t.Fatalf("no engine: %s", got)
+	}
+	// The engine of PolicyMiddleware applies to the handlers after it.
+	if got := serve(PolicyMiddleware(engine, ""), check); got != "own only" {
+		t.Fatalf("middleware engine: %s", got)
+	}
+	// SetPolicyEngine serves requests that did not pass through PolicyMiddleware.
+	SetPolicyEngine(engine)
+	if got := serve(check); got != "own only" {
+		t.Fatalf("default engine: %s", got)
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+)
+
+// Reason codes returned in the "reason" field of replay protection rejections.
+const (
+	ReasonAccessKeyReplayed = "access_key_replayed"
+	ReasonAccessKeyLegacy   = "access_key_legacy"
+	ReasonNonceStoreFailed  = "nonce_store_failed"
+)
+
+type replayProtection struct {
+	store       helpers.NonceStore
+	allowLegacy bool
+}
+
+// WithReplayProtection rejects Access-Keys whose nonce was already seen within the
+// validity window. Keys in the legacy key@timestamp format carry no nonce and are
+// accepted only when allowLegacy is true, e.g. while Laravel callers migrate.
+func WithReplayProtection(store helpers.NonceStore, allowLegacy bool) AccessKeyOption {
+	return func(options *accessKeyOptions) {
+		options.replay = &replayProtection{store: store, allowLegacy: allowLegacy}
+	}
+}
+
+func (r *replayProtection) check(ctx context.Context, claims *helpers.AccessKeyClaims, expireSeconds int64) (string, error) {
+	if claims.Nonce == "" {
+		if r.allowLegacy {
+			return "", nil
+		}
+		return ReasonAccessKeyLegacy, errors.New("legacy access key without nonce")
+	}
+
+	// Remember the nonce a little longer than the window so clock skew cannot reopen it.
+	ttl := time.Duration(expireSeconds+60) * time.Second
+	fresh, err := r.store.Remember(ctx, claims.Nonce, ttl)
+	if err != nil {
+		return ReasonNonceStoreFailed, fmt.Errorf("nonce store: %v", err)
+	}
+	if !fresh {
+		return ReasonAccessKeyReplayed, fmt.Errorf("access key nonce %s replayed", claims.Nonce)
+	}
+	return "", nil
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithMessage stops the chain and writes the Laravel style error body
+// ({"message": "..."}) used by every middleware in this package.
+func abortWithMessage(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{"message": message})
+	c.Abort()
+}
+
+// abortWithTranslation is abortWithMessage with the message resolved from the
+// catalog in the locale negotiated for the request.
+func abortWithTranslation(c *gin.Context, status int, messageID string) {
+	abortWithMessage(c, status, Translate(c, messageID, nil))
+}
+
+// abortWithReason is abortWithTranslation with a machine readable "reason" code
+// added to the body, so callers can tell e.g. a replayed key from a wrong one.
+// The message ID is "service.<reason>" when the catalog has it, "service.unauthorized" otherwise.
+func abortWithReason(c *gin.Context, status int, reason string) {
+	message := Translate(c, "service."+reason, nil)
+	if message == "service."+reason {
+		message = Translate(c, "service.unauthorized", nil)
+	}
+	c.JSON(status, gin.H{"message": message, "reason": reason})
+	c.Abort()
+}
+
+// Translate resolves messageID with the default translator in the request locale.
+func Translate(c *gin.Context, messageID string, params map[string]interface{}) string {
+	return helpers.DefaultTranslator().Translate(GetLocale(c), messageID, params)
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+// ReasonRouteForbidden is the "reason" of route policy denials.
+const ReasonRouteForbidden = "route_forbidden"
+
+// WithRoutePolicy evaluates policy inside AccessKeyMiddleware once the
+// caller is authenticated.
+func WithRoutePolicy(policy *helpers.RoutePolicy) AccessKeyOption {
+	return func(options *accessKeyOptions) {
+		options.routes = policy
+	}
+}
+
+// RoutePolicyMiddleware evaluates policy for requests already authenticated
+// by AccessKeyMiddleware, e.g. on a route group with stricter rules.
+func RoutePolicyMiddleware(policy *helpers.RoutePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkRoutePolicy(c, policy) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkRoutePolicy aborts with 403 when policy denies the caller the route. The
+// caller's scopes are those of callerScopes, so shared-key callers hold none.
+// Frontend requests bypassed by AccessKeyMiddleware never reach a WithRoutePolicy
+// policy; RoutePolicyMiddleware sees them without a caller.
+func checkRoutePolicy(c *gin.Context, policy *helpers.RoutePolicy) bool {
+	var callerID string
+	if caller, ok := GetCaller(c); ok {
+		callerID = caller.ID
+	}
+
+	decision := policy.Evaluate(callerID, callerScopes(c), c.Request.Method, c.Request.URL.Path)
+	if decision.Allowed {
+		return true
+	}
+	if decision.DryRun {
+		log.Printf("route policy (dry run): would deny: %s", decision.Reason)
+		return true
+	}
+
+	log.Printf("route policy: %s", decision.Reason)
+	body := gin.H{"reason": ReasonRouteForbidden}
+	if len(decision.RequiredScopes) > 0 {
+		body["message"] = Translate(c, "service.route_scope_required", map[string]interface{}{"scope": strings.Join(decision.RequiredScopes, ", ")})
+		body["required_scopes"] = decision.RequiredScopes
+	} else {
+		body["message"] = Translate(c, "service.route_forbidden", nil)
+	}
+	c.JSON(http.StatusForbidden, body)
+	c.Abort()
+	return false
+}
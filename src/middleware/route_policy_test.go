@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRoutePolicyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rules := []helpers.RouteRule{
+		{Path: "/internal/grades/:id", Methods: []string{"POST"}, Scopes: []string{"grades.write"}},
+		{Path: "/internal/**", Callers: []string{"admin"}},
+	}
+	enforced, err := helpers.NewRoutePolicy(helpers.RoutePolicyDocument{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dryRun, err := helpers.NewRoutePolicy(helpers.RoutePolicyDocument{Rules: rules, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(policy *helpers.RoutePolicy, caller *CallerIdentity, method, path string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			if caller != nil {
+				c.Set(CallerContextKey, caller)
+			}
+		}, RoutePolicyMiddleware(policy))
+		router.Any("/*path", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	grades := &CallerIdentity{ID: "grades", Scopes: []string{"grades.write"}}
+	if w := serve(enforced, grades, http.MethodPost, "/internal/grades/1"); w.Code != http.StatusOK {
+		t.Fatalf("caller with scope: status = %d", w.Code)
+	}
+	if w := serve(enforced, &CallerIdentity{ID: "admin"}, http.MethodGet, "/internal/students"); w.Code != http.StatusOK {
+		t.Fatalf("listed caller: status = %d", w.Code)
+	}
+	// Shared-key callers hold no scopes, whatever their identity says.
+	shared := &CallerIdentity{Shared: true, Scopes: []string{"grades.write"}}
+
+	for name, caller := range map[string]*CallerIdentity{"missing scope": {ID: "registration"}, "shared key": shared, "no caller": nil} {
+		t.Run(name, func(t *testing.T) {
+			w := serve(enforced, caller, http.MethodPost, "/internal/grades/1")
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d", w.Code)
+			}
+			var body struct {
+				Message        string   `json:"message"`
+				Reason         string   `json:"reason"`
+				RequiredScopes []string `json:"required_scopes"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Reason != ReasonRouteForbidden || !reflect.DeepEqual(body.RequiredScopes, []string{"grades.write"}) || body.Message != "Route ini membutuhkan scope grades.write." {
+				t.Fatalf("body = %s", w.Body.String())
+			}
+		})
+	}
+
+	w := serve(enforced, grades, http.MethodGet, "/internal/students")
+	if w.Code != http.StatusForbidden || w.Body.String() != `{"message":"Service ini tidak boleh memanggil route ini.","reason":"route_forbidden"}` {
+		t.Fatalf("caller rule denial = %d %s", w.Code, w.Body.String())
+	}
+
+	// A dry run logs the denial and lets the request through.
+	if w := serve(dryRun, shared, http.MethodPost, "/internal/grades/1"); w.Code != http.StatusOK {
+		t.Fatalf("dry run: status = %d", w.Code)
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/SIM-MBKM/mod-service/src/service"
+	"github.com/gin-gonic/gin"
+)
+
+func newSignatureTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("APP_KEY", laravelTestKey)
+	router := gin.New()
+	router.Use(AccessKeyMiddleware(laravelTestKey, 60, nil, WithRequestSignature(SignatureConfig{Required: true})))
+	router.Any("/students/:id", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusOK, gin.H{"body": string(body)})
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRequestSignatureFromService(t *testing.T) {
+	server := newSignatureTestServer(t)
+	svc := service.NewService(server.URL, nil)
+	svc.SignRequests = true
+
+	response, err := svc.RequestWithContext(context.Background(), http.MethodPost, "students/1?notify=1", map[string]interface{}{"nim": "5025201001"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The handler still reads the body hashed by the middleware.
+	if response["body"] != `{"nim":"5025201001"}` {
+		t.Fatalf("handler body = %v", response["body"])
+	}
+}
+
+func TestRequestSignatureRejectsTampering(t *testing.T) {
+	server := newSignatureTestServer(t)
+	security := helpers.NewSecurityAccessKeyWithKey(laravelTestKey)
+	signer := helpers.NewRequestSigner(laravelTestKey)
+	body := []byte(`{"nim":"5025201001"}`)
+	now := time.Now().Unix()
+
+	send := func(method, path string, body []byte, signature string, timestamp int64) int {
+		t.Helper()
+		accessKey, err := security.GenerateAccessKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(method, server.URL+path, bytes.NewReader(body))
+		req.Header.Set("Access-Key", accessKey)
+		if signature != "" {
+			req.Header.Set(helpers.SignatureHeader, signature)
+			req.Header.Set(helpers.SignatureTimestampHeader, strconv.FormatInt(timestamp, 10))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	signature := signer.Sign("POST", "/students/1", "", body, now)
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      []byte
+		signature string
+		timestamp int64
+		want      int
+	}{
+		{"signed", "POST", "/students/1", body, signature, now, http.StatusOK},
+		{"tampered body", "POST", "/students/1", []byte(`{"nim":"5025201002"}`), signature, now, http.StatusUnauthorized},
+		{"tampered method", "PUT", "/students/1", body, signature, now, http.StatusUnauthorized},
+		{"tampered path", "POST", "/students/2", body, signature, now, http.StatusUnauthorized},
+		{"tampered query", "POST", "/students/1?admin=1", body, signature, now, http.StatusUnauthorized},
+		{"tampered timestamp", "POST", "/students/1", body, signature, now + 1, http.StatusUnauthorized},
+		{"stale", "POST", "/students/1", body, signer.Sign("POST", "/students/1", "", body, now-600), now - 600, http.StatusUnauthorized},
+		{"unsigned", "POST", "/students/1", body, "", now, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := send(tt.method, tt.path, tt.body, tt.signature, tt.timestamp); got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("APP_KEY", laravelTestKey)
+	t.Setenv("APP_PREVIOUS_KEYS", "")
+
+	router := gin.New()
+	router.GET("/registrations/:id", ValidateSignature(SignedURLConfig{Relative: true, IgnoreQuery: []string{"utm_source"}}), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	signer := helpers.NewURLSignerFromEnv()
+	valid, err := signer.TemporarySign("/registrations/42", time.Now().Add(time.Minute), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := signer.TemporarySign("/registrations/42", time.Now().Add(-time.Minute), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"valid", valid, http.StatusOK},
+		{"ignored parameter", valid + "&utm_source=mail", http.StatusOK},
+		{"other parameter", valid + "&page=2", http.StatusForbidden},
+		{"other path", "/registrations/43?" + valid[len("/registrations/42?"):], http.StatusForbidden},
+		{"expired", expired, http.StatusForbidden},
+		{"unsigned", "/registrations/42", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			// Without LocaleMiddleware the message is in the default locale, id.
+			if tt.status == http.StatusForbidden && w.Body.String() != `{"message":"Tanda tangan tidak valid."}` {
+				t.Fatalf("body = %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/SIM-MBKM/mod-service/src/service"
+	"github.com/gin-gonic/gin"
+)
+
+// UserContextKey is the gin context key holding the authenticated *UserPrincipal.
+const UserContextKey = "mod-service.user"
+
+var (
+	// ErrUnauthenticated means the bearer token is missing, malformed or rejected.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden means the token is valid but the user may not access the service.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// UserPrincipal is the authenticated end user attached to a request.
+type UserPrincipal struct {
+	ID          string
+	Name        string
+	Email       string
+	Roles       []string
+	Permissions []string
+	Token       string
+	Attributes  map[string]interface{}
+}
+
+// TokenVerifier resolves a bearer token into a user principal.
+// Implementations return ErrUnauthenticated or ErrForbidden (optionally wrapped)
+// to select the response status; any other error is treated as an upstream failure.
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (*UserPrincipal, error)
+}
+
+// AuthServiceVerifier validates tokens by asking the auth service for the token's user.
+type AuthServiceVerifier struct {
+	Auth *service.AuthService
+}
+
+// NewAuthServiceVerifier creates a TokenVerifier backed by AuthService.
+func NewAuthServiceVerifier(auth *service.AuthService) *AuthServiceVerifier {
+	return &AuthServiceVerifier{Auth: auth}
+}
+
+// VerifyToken implements TokenVerifier.
+func (v *AuthServiceVerifier) VerifyToken(ctx context.Context, token string) (*UserPrincipal, error) {
+	user, err := v.Auth.UserWithContext(ctx, token)
+	if err != nil {
+		var serviceErr *service.Error
+		if errors.As(err, &serviceErr) {
+			switch serviceErr.Code {
+			case http.StatusUnauthorized:
+				return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+			case http.StatusForbidden:
+				return nil, fmt.Errorf("%w: %v", ErrForbidden, err)
+			}
+		}
+		return nil, err
+	}
+
+	principal := PrincipalFromMap(user)
+	principal.Token = token
+	return principal, nil
+}
+
+// PrincipalFromMap builds a UserPrincipal from a decoded user object.
+// Unknown fields are kept in Attributes.
+func PrincipalFromMap(user map[string]interface{}) *UserPrincipal {
+	principal := &UserPrincipal{Attributes: map[string]interface{}{}}
+	for key, value := range user {
+		switch key {
+		case "id":
+			if value != nil {
+				principal.ID = helpers.ClaimString(value)
+			}
+		case "name":
+			principal.Name, _ = value.(string)
+		case "email":
+			principal.Email, _ = value.(string)
+		case "roles":
+			principal.Roles = toStringSlice(value)
+		case "permissions":
+			principal.Permissions = toStringSlice(value)
+		default:
+			principal.Attributes[key] = value
+		}
+	}
+	if principal.ID == "" {
+		if sub, ok := user["sub"]; ok && sub != nil {
+			principal.ID = helpers.ClaimString(sub)
+		}
+	}
+	return principal
+}
+
+// UserAuthMiddleware authenticates the end user from the "Authorization: Bearer" header
+// and stores the resulting *UserPrincipal in the gin context.
+func UserAuthMiddleware(verifier TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := BearerToken(c)
+		if token == "" {
+			abortWithTranslation(c, http.StatusUnauthorized, "auth.unauthenticated")
+			return
+		}
+
+		principal, err := verifier.VerifyToken(c.Request.Context(), token)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrForbidden):
+			abortWithTranslation(c, http.StatusForbidden, "auth.forbidden")
+			return
+		case errors.Is(err, ErrUnauthenticated):
+			abortWithTranslation(c, http.StatusUnauthorized, "auth.unauthenticated")
+			return
+		default:
+			log.Printf("user auth: token verification failed: %v", err)
+			abortWithTranslation(c, http.StatusServiceUnavailable, "auth.unavailable")
+			return
+		}
+
+		c.Set(UserContextKey, principal)
+		c.Next()
+	}
+}
+
+// BearerToken returns the token from the "Authorization: Bearer" header, or "".
+func BearerToken(c *gin.Context) string {
+	header := strings.TrimSpace(c.GetHeader("Authorization"))
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+// GetUser returns the authenticated user stored by UserAuthMiddleware.
+func GetUser(c *gin.Context) (*UserPrincipal, bool) {
+	value, exists := c.Get(UserContextKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*UserPrincipal)
+	return principal, ok && principal != nil
+}
+
+// MustGetUser returns the authenticated user and panics when the middleware did not run.
+func MustGetUser(c *gin.Context) *UserPrincipal {
+	principal, ok := GetUser(c)
+	if !ok {
+		panic("middleware: no user principal in context, is UserAuthMiddleware installed?")
+	}
+	return principal
+}
+
+func toStringSlice(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case string:
+		if v == "" {
+			return nil
+		}
+		return strings.Fields(strings.ReplaceAll(v, ",", " "))
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item := item.(type) {
+			case string:
+				result = append(result, item)
+			case map[string]interface{}:
+				// Spatie style role objects: {"name": "admin", ...}
+				if name, ok := item["name"].(string); ok {
+					result = append(result, name)
+				}
+			}
+		}
+		return result
+	}
+	return nil
+}
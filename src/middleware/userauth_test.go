@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPrincipalFromMapNumericID(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"small id", `{"id": 42}`, "42"},
+		{"large id", `{"id": 1234567}`, "1234567"},
+		{"bigint id", `{"id": 9007199254740991}`, "9007199254740991"},
+		{"string id", `{"id": "01HZX3"}`, "01HZX3"},
+		{"sub fallback", `{"sub": 1234567}`, "1234567"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.body), &user); err != nil {
+				t.Fatal(err)
+			}
+			if got := PrincipalFromMap(user).ID; got != tt.want {
+				t.Fatalf("ID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// AbortWithValidationError answers 422 with Laravel's validation error shape
+// ({"message": "...", "errors": {"field": ["..."]}}) in the request locale.
+// err is usually the result of c.ShouldBind; other errors are reported as invalid input.
+func AbortWithValidationError(c *gin.Context, err error) {
+	translator := helpers.DefaultTranslator()
+	locale := GetLocale(c)
+	fields := map[string][]string{}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fieldError := range validationErrors {
+			messageID := "validation." + fieldError.Tag()
+			params := map[string]interface{}{
+				"attribute": fieldError.Field(),
+				"param":     fieldError.Param(),
+			}
+			message := translator.Translate(locale, messageID, params)
+			if message == messageID {
+				message = translator.Translate(locale, "validation.invalid", params)
+			}
+			fields[fieldError.Field()] = append(fields[fieldError.Field()], message)
+		}
+	}
+
+	count := 0
+	for _, messages := range fields {
+		count += len(messages)
+	}
+	if count == 0 {
+		count = 1
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"message": translator.Choice(locale, "validation.failed", count, nil),
+		"errors":  fields,
+	})
+	c.Abort()
+}
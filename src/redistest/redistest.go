@@ -0,0 +1,635 @@
+// Package redistest provides an in-process Redis stand-in for tests, in the
+// spirit of net/http/httptest. It speaks RESP2 and implements the commands
+// used by this module: PING, AUTH, SELECT, GET, SET, DEL, EXISTS, TTL, LPUSH,
+// RPUSH, LPOP, RPOP, BRPOP, LLEN, LRANGE, ZADD, ZCARD and ZRANGE.
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a Redis stand-in listening on a local port.
+type Server struct {
+	// Addr is the "host:port" the server listens on.
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu          sync.Mutex
+	password    string
+	dbs         map[int]map[string]*entry
+	commands    [][]string
+	connections int
+	conns       map[net.Conn]bool
+	offset      time.Duration
+	// changed is closed and replaced whenever a list receives items.
+	changed chan struct{}
+	closed  chan struct{}
+}
+
+type entry struct {
+	str       *string
+	list      []string
+	zset      map[string]float64
+	expiresAt time.Time
+}
+
+// NewServer starts a server on 127.0.0.1. It panics when it cannot listen,
+// like httptest.NewServer.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("redistest: failed to listen: %v", err))
+	}
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		listener: listener,
+		dbs:      map[int]map[string]*entry{},
+		conns:    map[net.Conn]bool{},
+		changed:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close stops the server and closes every client connection.
+func (s *Server) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+		s.mu.Unlock()
+		return
+	default:
+	}
+	close(s.closed)
+	s.listener.Close()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// RequirePass makes new connections send AUTH password before other commands,
+// like Redis's requirepass.
+func (s *Server) RequirePass(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.password = password
+}
+
+// Commands returns the commands received so far, AUTH and SELECT included.
+func (s *Server) Commands() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string{}, s.commands...)
+}
+
+// Connections returns the number of connections accepted so far.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// FastForward moves the server clock, expiring keys whose TTL has passed.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+}
+
+// List returns the items of the list at key in database 0.
+func (s *Server) List(key string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.lookup(0, key); e != nil {
+		return append([]string{}, e.list...)
+	}
+	return nil
+}
+
+// SortedSet returns the members and scores of the sorted set at key in database 0.
+func (s *Server) SortedSet(key string) map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members := map[string]float64{}
+	if e := s.lookup(0, key); e != nil {
+		for member, score := range e.zset {
+			members[member] = score
+		}
+	}
+	return members
+}
+
+// Push appends raw items to the list at key in database 0, e.g. to inject
+// payloads a client would never write.
+func (s *Server) Push(key string, items ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.create(0, key)
+	e.list = append(e.list, items...)
+	s.notify()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.connections++
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// client is the state of one connection.
+type client struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	db       int
+	password string
+	authed   bool
+}
+
+func (s *Server) handle(conn net.Conn) {
+	s.mu.Lock()
+	state := &client{conn: conn, reader: bufio.NewReader(conn), password: s.password, authed: s.password == ""}
+	s.mu.Unlock()
+	writer := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(state.reader)
+		if err != nil {
+			return
+		}
+		reply := s.execute(state, args)
+		writeReply(writer, reply)
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// redisError is written as an error reply.
+type redisError string
+
+func (s *Server) execute(state *client, args []string) interface{} {
+	if len(args) == 0 {
+		return redisError("ERR empty command")
+	}
+	name := strings.ToUpper(args[0])
+
+	s.mu.Lock()
+	s.commands = append(s.commands, append([]string{}, args...))
+	s.mu.Unlock()
+
+	if name == "AUTH" {
+		if len(args) != 2 || args[1] != state.password {
+			return redisError("WRONGPASS invalid username-password pair")
+		}
+		state.authed = true
+		return "OK"
+	}
+	if !state.authed {
+		return redisError("NOAUTH Authentication required.")
+	}
+	if name == "BRPOP" {
+		return s.brpop(state, args[1:])
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch name {
+	case "PING":
+		return "PONG"
+	case "SELECT":
+		db, err := strconv.Atoi(arg(args, 1))
+		if err != nil || db < 0 || db > 15 {
+			return redisError("ERR DB index is out of range")
+		}
+		state.db = db
+		return "OK"
+	case "GET":
+		e := s.lookup(state.db, arg(args, 1))
+		if e == nil {
+			return nil
+		}
+		if e.str == nil {
+			return wrongType
+		}
+		return bulk(*e.str)
+	case "SET":
+		return s.set(state.db, args[1:])
+	case "DEL", "EXISTS":
+		count := int64(0)
+		for _, key := range args[1:] {
+			if s.lookup(state.db, key) != nil {
+				count++
+				if name == "DEL" {
+					delete(s.db(state.db), key)
+				}
+			}
+		}
+		return count
+	case "TTL":
+		e := s.lookup(state.db, arg(args, 1))
+		switch {
+		case e == nil:
+			return int64(-2)
+		case e.expiresAt.IsZero():
+			return int64(-1)
+		}
+		return int64(e.expiresAt.Sub(s.now()).Round(time.Second) / time.Second)
+	case "LPUSH", "RPUSH":
+		if len(args) < 3 {
+			return wrongArgs(name)
+		}
+		e := s.create(state.db, args[1])
+		if e.str != nil || e.zset != nil {
+			return wrongType
+		}
+		for _, item := range args[2:] {
+			if name == "LPUSH" {
+				e.list = append([]string{item}, e.list...)
+			} else {
+				e.list = append(e.list, item)
+			}
+		}
+		s.notify()
+		return int64(len(e.list))
+	case "LPOP", "RPOP":
+		item, ok := s.pop(state.db, arg(args, 1), name == "LPOP")
+		if !ok {
+			return nil
+		}
+		return bulk(item)
+	case "LLEN":
+		if e := s.lookup(state.db, arg(args, 1)); e != nil {
+			return int64(len(e.list))
+		}
+		return int64(0)
+	case "LRANGE":
+		e := s.lookup(state.db, arg(args, 1))
+		start, err1 := strconv.Atoi(arg(args, 2))
+		stop, err2 := strconv.Atoi(arg(args, 3))
+		if err1 != nil || err2 != nil {
+			return redisError("ERR value is not an integer or out of range")
+		}
+		if e == nil {
+			return []interface{}{}
+		}
+		return rangeOf(e.list, start, stop)
+	case "ZADD":
+		if len(args) < 4 || len(args)%2 != 0 {
+			return wrongArgs(name)
+		}
+		e := s.create(state.db, args[1])
+		if e.str != nil || e.list != nil {
+			return wrongType
+		}
+		if e.zset == nil {
+			e.zset = map[string]float64{}
+		}
+		added := int64(0)
+		for i := 2; i < len(args); i += 2 {
+			score, err := strconv.ParseFloat(args[i], 64)
+			if err != nil {
+				return redisError("ERR value is not a valid float")
+			}
+			if _, exists := e.zset[args[i+1]]; !exists {
+				added++
+			}
+			e.zset[args[i+1]] = score
+		}
+		return added
+	case "ZCARD":
+		if e := s.lookup(state.db, arg(args, 1)); e != nil {
+			return int64(len(e.zset))
+		}
+		return int64(0)
+	case "ZRANGE":
+		e := s.lookup(state.db, arg(args, 1))
+		start, err1 := strconv.Atoi(arg(args, 2))
+		stop, err2 := strconv.Atoi(arg(args, 3))
+		if err1 != nil || err2 != nil {
+			return redisError("ERR value is not an integer or out of range")
+		}
+		if e == nil {
+			return []interface{}{}
+		}
+		members := make([]string, 0, len(e.zset))
+		for member := range e.zset {
+			members = append(members, member)
+		}
+		sort.Slice(members, func(i, j int) bool {
+			if e.zset[members[i]] != e.zset[members[j]] {
+				return e.zset[members[i]] < e.zset[members[j]]
+			}
+			return members[i] < members[j]
+		})
+		return rangeOf(members, start, stop)
+	}
+	return redisError(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+}
+
+var wrongType = redisError("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+func wrongArgs(name string) redisError {
+	return redisError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// set implements SET key value [NX|XX] [EX seconds|PX milliseconds].
+func (s *Server) set(db int, args []string) interface{} {
+	if len(args) < 2 {
+		return wrongArgs("SET")
+	}
+	key, value := args[0], args[1]
+	var nx, xx bool
+	var ttl time.Duration
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return redisError("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return redisError("ERR invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if strings.ToUpper(args[i]) == "PX" {
+				unit = time.Millisecond
+			}
+			ttl = time.Duration(n) * unit
+			i++
+		default:
+			return redisError("ERR syntax error")
+		}
+	}
+
+	exists := s.lookup(db, key) != nil
+	if (nx && exists) || (xx && !exists) {
+		return nil
+	}
+	e := &entry{str: &value}
+	if ttl > 0 {
+		e.expiresAt = s.now().Add(ttl)
+	}
+	s.db(db)[key] = e
+	return "OK"
+}
+
+// brpop implements BRPOP key [key ...] timeout, waiting without holding s.mu.
+func (s *Server) brpop(state *client, args []string) interface{} {
+	if len(args) < 2 {
+		return wrongArgs("BRPOP")
+	}
+	keys := args[:len(args)-1]
+	seconds, err := strconv.ParseFloat(args[len(args)-1], 64)
+	if err != nil || seconds < 0 {
+		return redisError("ERR timeout is not a float or out of range")
+	}
+	var timeout <-chan time.Time
+	if seconds > 0 {
+		timer := time.NewTimer(time.Duration(seconds * float64(time.Second)))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	// Like Redis, stop waiting when the client goes away, so an abandoned
+	// BRPOP cannot take the next item.
+	gone := watchClose(state)
+	defer gone.stop()
+
+	for {
+		s.mu.Lock()
+		for _, key := range keys {
+			if item, ok := s.pop(state.db, key, false); ok {
+				s.mu.Unlock()
+				return []interface{}{bulk(key), bulk(item)}
+			}
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout:
+			return nilArray{}
+		case <-gone.closed:
+			return nilArray{}
+		case <-s.closed:
+			return nilArray{}
+		}
+	}
+}
+
+type closeWatch struct {
+	conn   net.Conn
+	closed chan struct{}
+	done   chan struct{}
+}
+
+// watchClose reports on closed when the client disconnects while blocked. A
+// blocked client sends nothing, so it peeks at the connection until stop.
+func watchClose(state *client) *closeWatch {
+	w := &closeWatch{conn: state.conn, closed: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		_, err := state.reader.Peek(1)
+		var netErr net.Error
+		if err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+			close(w.closed)
+		}
+	}()
+	return w
+}
+
+func (w *closeWatch) stop() {
+	w.conn.SetReadDeadline(time.Now())
+	<-w.done
+	w.conn.SetReadDeadline(time.Time{})
+}
+
+func (s *Server) pop(db int, key string, left bool) (string, bool) {
+	e := s.lookup(db, key)
+	if e == nil || len(e.list) == 0 {
+		return "", false
+	}
+	var item string
+	if left {
+		item, e.list = e.list[0], e.list[1:]
+	} else {
+		item, e.list = e.list[len(e.list)-1], e.list[:len(e.list)-1]
+	}
+	if len(e.list) == 0 {
+		delete(s.db(db), key)
+	}
+	return item, true
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *Server) db(index int) map[string]*entry {
+	db, ok := s.dbs[index]
+	if !ok {
+		db = map[string]*entry{}
+		s.dbs[index] = db
+	}
+	return db
+}
+
+// lookup returns the live entry at key. s.mu must be held.
+func (s *Server) lookup(db int, key string) *entry {
+	e, ok := s.db(db)[key]
+	if !ok {
+		return nil
+	}
+	if !e.expiresAt.IsZero() && !s.now().Before(e.expiresAt) {
+		delete(s.db(db), key)
+		return nil
+	}
+	return e
+}
+
+// create returns the entry at key, adding an empty one. s.mu must be held.
+func (s *Server) create(db int, key string) *entry {
+	if e := s.lookup(db, key); e != nil {
+		return e
+	}
+	e := &entry{}
+	s.db(db)[key] = e
+	return e
+}
+
+// notify wakes blocked BRPOP commands. s.mu must be held.
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func arg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+func rangeOf(items []string, start, stop int) []interface{} {
+	n := len(items)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	stop = min(stop, n-1)
+	result := []interface{}{}
+	for i := start; i <= stop; i++ {
+		result = append(result, bulk(items[i]))
+	}
+	return result
+}
+
+// bulk is written as a bulk string, plain strings as simple strings.
+type bulk string
+
+// nilArray is written as the nil array "*-1".
+type nilArray struct{}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		// Inline command, as typed in telnet.
+		return strings.Fields(line), nil
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 || count > 1<<20 {
+		return nil, errors.New("redistest: invalid multibulk length")
+	}
+	args := make([]string, 0, min(count, 64))
+	for i := 0; i < count; i++ {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, errors.New("redistest: expected bulk string")
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > 512<<20 {
+			return nil, errors.New("redistest: invalid bulk length")
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(w *bufio.Writer, reply interface{}) {
+	switch reply := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case nilArray:
+		w.WriteString("*-1\r\n")
+	case string:
+		fmt.Fprintf(w, "+%s\r\n", reply)
+	case bulk:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(reply), reply)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", reply)
+	case redisError:
+		fmt.Fprintf(w, "-%s\r\n", reply)
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(reply))
+		for _, item := range reply {
+			writeReply(w, item)
+		}
+	}
+}
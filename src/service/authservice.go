@@ -1,13 +1,58 @@
 package service
 
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultUserURI is the AuthService endpoint that resolves the user owning a bearer token.
+const DefaultUserURI = "user"
+
 // AuthService extends Service with additional behavior.
 type AuthService struct {
 	Service *Service
+	UserURI string
 }
 
 // NewAuthService creates a new instance of AuthService.
 func NewAuthService(baseURI string, asyncURIs []string) *AuthService {
 	return &AuthService{
 		Service: NewService(baseURI, asyncURIs),
+		UserURI: DefaultUserURI,
+	}
+}
+
+// User resolves the user that owns the given bearer token.
+// The returned map is the "data" object of the response when present,
+// otherwise the whole response body.
+func (a *AuthService) User(token string) (map[string]interface{}, error) {
+	return a.UserWithContext(context.Background(), token)
+}
+
+// UserWithContext is User bound to ctx.
+func (a *AuthService) UserWithContext(ctx context.Context, token string) (map[string]interface{}, error) {
+	if token == "" {
+		return nil, errors.New("empty user token")
+	}
+
+	uri := a.UserURI
+	if uri == "" {
+		uri = DefaultUserURI
+	}
+
+	// One AuthService serves every request of UserAuthMiddleware, so the
+	// response is not kept in the shared Service.HTTPResponse.
+	response, err := a.Service.request(ctx, "GET", uri, nil, token, false)
+	if err != nil {
+		return response, err
+	}
+	if response == nil {
+		return nil, fmt.Errorf("empty response from %s", uri)
+	}
+
+	if data, ok := response["data"].(map[string]interface{}); ok {
+		return data, nil
 	}
+	return response, nil
 }
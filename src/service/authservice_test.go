@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAuthServiceUserConcurrent(t *testing.T) {
+	t.Setenv("APP_KEY", "secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"id": token},
+		})
+	}))
+	defer server.Close()
+
+	auth := NewAuthService(server.URL, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+			user, err := auth.UserWithContext(context.Background(), token)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if user["id"] != token {
+				t.Errorf("user id = %v, want %s", user["id"], token)
+			}
+		}(strings.Repeat("t", i+1))
+	}
+	wg.Wait()
+
+	if auth.Service.HTTPResponse != nil {
+		t.Error("UserWithContext stored the response on the shared Service")
+	}
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchMode selects how a batch reports failures.
+type BatchMode int
+
+const (
+	// BestEffort returns every result and leaves failures in Result.Err.
+	BestEffort BatchMode = iota
+	// AllOrNothing returns a *BatchError when any call fails.
+	AllOrNothing
+)
+
+// ErrBatchCanceled is set on calls that never started because an earlier call
+// failed with BatchOptions.CancelOnFailure, or because the batch context ended.
+var ErrBatchCanceled = errors.New("batch: call canceled before it started")
+
+// Call is one request of a batch.
+type Call struct {
+	// Name identifies the call in results and errors, e.g. "profile".
+	Name    string
+	Service *Service
+	Method  string
+	URI     string
+	Opts    map[string]interface{}
+	// Token is the user token; empty forwards the token carried by the batch context.
+	Token string
+}
+
+// BatchOptions configures Batch.
+type BatchOptions struct {
+	// Concurrency limits calls in flight; zero or less runs every call at once.
+	Concurrency int
+	Mode        BatchMode
+	// CancelOnFailure cancels the remaining calls after the first failure.
+	CancelOnFailure bool
+}
+
+// Result is the outcome of one Call, in the same position as the call.
+type Result struct {
+	Name     string
+	Response map[string]interface{}
+	Err      error
+	Duration time.Duration
+}
+
+// Results are the outcomes of a batch, in call order.
+type Results []Result
+
+// Get returns the result of the call with the given name.
+func (r Results) Get(name string) (Result, bool) {
+	for _, result := range r {
+		if result.Name == name {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+// Failed returns the results that carry an error.
+func (r Results) Failed() Results {
+	var failed Results
+	for _, result := range r {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// BatchError reports the failed calls of an AllOrNothing batch.
+type BatchError struct {
+	Failed Results
+}
+
+// Error implements error.
+func (e *BatchError) Error() string {
+	parts := make([]string, 0, len(e.Failed))
+	for _, result := range e.Failed {
+		parts = append(parts, fmt.Sprintf("%s: %v", result.Name, result.Err))
+	}
+	return fmt.Sprintf("batch: %d call(s) failed: %s", len(e.Failed), strings.Join(parts, "; "))
+}
+
+// Unwrap exposes the per call errors to errors.Is and errors.As.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, result := range e.Failed {
+		errs = append(errs, result.Err)
+	}
+	return errs
+}
+
+// Batch runs heterogeneous service calls concurrently and returns their results
+// in call order. Calls never write Service.HTTPResponse, so one Service may
+// appear in several calls.
+func Batch(ctx context.Context, calls []Call, options BatchOptions) (Results, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	limit := options.Concurrency
+	if limit <= 0 || limit > len(calls) {
+		limit = len(calls)
+	}
+	semaphore := make(chan struct{}, limit)
+
+	results := make(Results, len(calls))
+	var wg sync.WaitGroup
+
+	for i, call := range calls {
+		results[i].Name = call.Name
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ErrBatchCanceled
+			continue
+		}
+		// The slot may have been granted just as the batch got canceled.
+		if ctx.Err() != nil {
+			<-semaphore
+			results[i].Err = ErrBatchCanceled
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, call Call) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			started := time.Now()
+			response, err := call.Service.request(ctx, call.Method, call.URI, call.Opts, call.Token, false)
+			results[i].Response = response
+			results[i].Err = err
+			results[i].Duration = time.Since(started)
+
+			if err != nil && options.CancelOnFailure {
+				cancel()
+			}
+		}(i, call)
+	}
+	wg.Wait()
+
+	if options.Mode == AllOrNothing {
+		if failed := results.Failed(); len(failed) > 0 {
+			return results, &BatchError{Failed: failed}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+)
+
+// Error is returned by Service calls that fail. Message is localized to the
+// locale of the request that made the call and is safe to show to clients.
+type Error struct {
+	// Code is the HTTP status of the upstream response, 0 when it could not be reached.
+	Code    int
+	Status  string
+	Message string
+	// Errors holds the "errors" object of the upstream response, if any.
+	Errors interface{}
+	// Err is the underlying transport error for unreachable services.
+	Err error
+}
+
+// Error implements error. It keeps the text previously returned by Service:
+// the HTTP status line, or the transport error.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Status
+}
+
+// Unwrap returns the underlying transport error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// name identifies the upstream service in messages.
+func (s *Service) name() string {
+	if parsed, err := url.Parse(s.BaseURI); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return s.BaseURI
+}
+
+func (s *Service) unreachableError(ctx context.Context, err error) *Error {
+	return &Error{
+		Message: helpers.T(ctx, "service.unreachable", map[string]interface{}{"service": s.name()}),
+		Err:     err,
+	}
+}
+
+func (s *Service) statusError(ctx context.Context, code int, status string, body map[string]interface{}) *Error {
+	message, _ := body["message"].(string)
+	if message == "" {
+		message = helpers.T(ctx, "service.request_failed", map[string]interface{}{
+			"service": s.name(),
+			"status":  code,
+		})
+	}
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+		Errors:  body["errors"],
+	}
+}
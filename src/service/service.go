@@ -2,15 +2,17 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/SIM-MBKM/mod-service/src/broker"
 	"github.com/SIM-MBKM/mod-service/src/helpers"
 )
 
@@ -19,20 +21,80 @@ type Service struct {
 	AsyncURIs    []string
 	Client       *http.Client
 	HTTPResponse *http.Response
+	// Broker, when set, carries calls to AsyncURIs instead of a background HTTP request.
+	Broker broker.Broker
+	// BrokerTopic is the topic async calls are published to; it defaults to the BaseURI host.
+	BrokerTopic string
+	// SignRequests adds an HMAC signature over method, path, query, body and timestamp.
+	SignRequests bool
+	// NonceKeys sends single use Access-Keys carrying a random nonce, for servers
+	// that enable replay protection.
+	NonceKeys bool
+	// Keyring, when set, issues Access-Keys with its active key instead of APP_KEY.
+	Keyring *helpers.Keyring
+	// CallerID and CallerKey, when set, identify this service with its own
+	// credential instead of the shared APP_KEY.
+	CallerID  string
+	CallerKey string
+	// AccessKeyVersion selects the Access-Key format: helpers.AccessKeyV2, or 1
+	// for key@timestamp. Zero uses helpers.AccessKeyVersion (ACCESS_KEY_VERSION).
+	AccessKeyVersion int
+	// Audience is the aud claim of v2 Access-Keys; it defaults to the BaseURI host.
+	Audience string
+	// Scopes are the scopes claimed by v2 Access-Keys. Receiving services only
+	// honour them on keys made with this service's own caller credential.
+	Scopes []string
+
+	// security is the Access-Key encrypter validated by NewService, or the
+	// error APP_HASH and APP_CIPHER produced.
+	security    *helpers.SecurityAccessKey
+	securityErr error
 }
 
+// NewService creates a Service. APP_HASH and APP_CIPHER are validated here; when
+// they are invalid the error is logged and returned by every request that
+// issues a v1 Access-Key.
 func NewService(baseURI string, asyncURIs []string) *Service {
+	helpers.LoadEnv()
+	security, err := helpers.NewSecurityAccessKeyFromEnv()
+	if err != nil {
+		log.Printf("service: invalid access key configuration: %v", err)
+	}
 	return &Service{
-		BaseURI:   strings.TrimRight(baseURI, "/") + "/",
-		AsyncURIs: asyncURIs,
-		Client:    &http.Client{Timeout: 30 * time.Second},
+		BaseURI:     strings.TrimRight(baseURI, "/") + "/",
+		AsyncURIs:   asyncURIs,
+		Client:      &http.Client{Timeout: 30 * time.Second},
+		security:    security,
+		securityErr: err,
 	}
 }
 
+// accessKeySecurity returns the encrypter for v1 Access-Keys. Services built
+// as struct literals validate the environment on every call.
+func (s *Service) accessKeySecurity() (*helpers.SecurityAccessKey, error) {
+	if s.security != nil || s.securityErr != nil {
+		return s.security, s.securityErr
+	}
+	return helpers.NewSecurityAccessKeyFromEnv()
+}
+
+// secret is the APP_KEY embedded in Access-Keys and used to sign requests.
+func (s *Service) secret() string {
+	if s.CallerKey != "" {
+		return s.CallerKey
+	}
+	if s.Keyring != nil {
+		return s.Keyring.Active().Secret
+	}
+	return helpers.GetEnv("APP_KEY", "secret")
+}
+
 // getHeaders generates the headers for the request.
-func (s *Service) getHeaders(token string) (map[string]string, error) {
+// When token is empty, the user token captured in ctx is forwarded unless
+// forwarding was disabled with helpers.WithoutTokenForwarding.
+func (s *Service) getHeaders(ctx context.Context, token string) (map[string]string, error) {
 	helpers.LoadEnv()
-	security := helpers.NewSecurityAccessKey()
+	secret := s.secret()
 
 	// Mengambil waktu saat ini
 	currentTime := time.Now()
@@ -40,20 +102,42 @@ func (s *Service) getHeaders(token string) (map[string]string, error) {
 	// Mengubah waktu ke timestamp Unix (jumlah detik sejak epoch)
 	timestamp := currentTime.Unix()
 
-	// Mengonversi timestamp ke string
-	timestampString := strconv.Itoa(int(timestamp))
+	var accessKey string
+	var err error
+	if s.accessKeyVersion() == helpers.AccessKeyV2 {
+		accessKey, err = s.accessKeyV2(secret)
+	} else {
+		var security *helpers.SecurityAccessKey
+		if security, err = s.accessKeySecurity(); err != nil {
+			return nil, err
+		}
+		if s.Keyring != nil || s.CallerKey != "" {
+			security = security.WithKey(secret)
+		}
+
+		var nonce string
+		if s.NonceKeys {
+			if nonce, err = helpers.NewNonce(); err != nil {
+				return nil, err
+			}
+		}
 
-	accessKey, err := security.Encrypt(
-		helpers.GetEnv("APP_KEY", "secret") + "@" + timestampString,
-	)
+		accessKey, err = security.Encrypt(
+			helpers.FormatAccessKey(secret, timestamp, nonce),
+		)
+	}
 
-	localeInstance := helpers.GetInstance()
-	locale := localeInstance.GetLocale()
+	locale := helpers.LocaleFromContext(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
+	forward := !helpers.TokenForwardingDisabled(ctx)
+	if token == "" && forward {
+		token = helpers.UserTokenFromContext(ctx)
+	}
+
 	var userToken string
 
 	if token != "" {
@@ -70,84 +154,156 @@ func (s *Service) getHeaders(token string) (map[string]string, error) {
 		"App-Locale":    locale,
 	}
 
+	if s.CallerKey != "" {
+		headers[helpers.CallerHeader] = s.CallerID
+	} else if s.Keyring != nil {
+		headers[helpers.KeyIDHeader] = s.Keyring.Active().ID
+	}
+
+	if onBehalfOf := helpers.OnBehalfOfFromContext(ctx); onBehalfOf != "" && forward {
+		headers["On-Behalf-Of"] = onBehalfOf
+	}
+
 	return headers, nil
 }
 
+func (s *Service) accessKeyVersion() int {
+	if s.AccessKeyVersion != 0 {
+		return s.AccessKeyVersion
+	}
+	return helpers.AccessKeyVersion()
+}
+
+// accessKeyV2 issues a v2 Access-Key for this service's audience and scopes.
+func (s *Service) accessKeyV2(secret string) (string, error) {
+	audience := s.Audience
+	if audience == "" {
+		audience = s.name()
+	}
+	claims, err := helpers.NewAccessKeyClaims(audience, s.Scopes...)
+	if err != nil {
+		return "", err
+	}
+	if s.CallerID != "" {
+		claims.Issuer = s.CallerID
+	}
+	return helpers.EncryptAccessKeyClaims(secret, claims)
+}
+
 // Request sends an HTTP request.
 func (s *Service) Request(method, uri string, opts map[string]interface{}, token string) (map[string]interface{}, error) {
+	return s.RequestWithContext(context.Background(), method, uri, opts, token)
+}
+
+// RequestWithContext sends an HTTP request bound to ctx. Pass c.Request.Context()
+// from a gin handler to forward the user token captured by middleware.ForwardUserToken.
+func (s *Service) RequestWithContext(ctx context.Context, method, uri string, opts map[string]interface{}, token string) (map[string]interface{}, error) {
+	return s.request(ctx, method, uri, opts, token, true)
+}
+
+// request sends the HTTP request. When keepResponse is false the response is not
+// stored in s.HTTPResponse, which makes concurrent calls on one Service safe.
+func (s *Service) request(ctx context.Context, method, uri string, opts map[string]interface{}, token string, keepResponse bool) (map[string]interface{}, error) {
 	url := s.BaseURI + uri
 	var body []byte
 	var err error
 
 	if opts != nil {
-		body, err = json.Marshal(opts)
+		// Fields tagged `encrypt:"true"` leave this service encrypted.
+		payload, err := helpers.EncryptFields(opts)
+		if err != nil {
+			return nil, err
+		}
+		body, err = json.Marshal(payload)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	headers, err := s.getHeaders(token)
+	headers, err := s.getHeaders(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
+	if s.SignRequests {
+		timestamp := time.Now().Unix()
+		signer := helpers.NewRequestSigner(s.secret())
+		headers[helpers.SignatureTimestampHeader] = strconv.FormatInt(timestamp, 10)
+		headers[helpers.SignatureHeader] = signer.Sign(method, req.URL.EscapedPath(), req.URL.RawQuery, body, timestamp)
+	}
+
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
 
+	if s.isAsync(uri) && s.Broker != nil {
+		return nil, s.publish(ctx, method, uri, headers, body)
+	}
+
 	if s.isAsync(uri) {
+		// Async calls outlive the inbound request, keep its values but not its cancellation.
+		req = req.WithContext(context.WithoutCancel(ctx))
 		go func() {
-			s.HTTPResponse, _ = s.Client.Do(req)
-
+			resp, err := s.Client.Do(req)
+			if err != nil {
+				return
+			}
+			if keepResponse {
+				s.HTTPResponse = resp
+				return
+			}
+			resp.Body.Close()
 		}()
 		return nil, nil
-	} else {
-		s.HTTPResponse, err = s.Client.Do(req)
-
-		if err != nil {
-			return nil, err
-		}
-		// return s.HTTPResponse, nil
-		jsonResponse, err := s.Response()
-
-		if err != nil {
-			return nil, err
-		}
+	}
 
-		return jsonResponse, nil
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, s.unreachableError(ctx, err)
+	}
+	if keepResponse {
+		s.HTTPResponse = resp
 	}
+
+	return s.readResponse(ctx, resp)
 }
 
 // Response processes the HTTP response.
 func (s *Service) Response() (map[string]interface{}, error) {
-	if s.HTTPResponse == nil {
+	return s.readResponse(context.Background(), s.HTTPResponse)
+}
+
+// readResponse decodes resp, localizing errors in the locale carried by ctx.
+func (s *Service) readResponse(ctx context.Context, resp *http.Response) (map[string]interface{}, error) {
+	if resp == nil {
 		return map[string]interface{}{
 			"status": "success",
 			"data":   nil,
 		}, nil
 	}
 
-	defer s.HTTPResponse.Body.Close()
-	body, err := io.ReadAll(s.HTTPResponse.Body)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	if s.HTTPResponse.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusOK {
 		var errorResponse map[string]interface{}
 		_ = json.Unmarshal(body, &errorResponse)
 
+		serviceErr := s.statusError(ctx, resp.StatusCode, resp.Status, errorResponse)
 		return map[string]interface{}{
 			"status":  "error",
-			"code":    s.HTTPResponse.StatusCode,
-			"message": s.HTTPResponse.Status,
-			"errors":  errorResponse["errors"],
-		}, errors.New(s.HTTPResponse.Status)
+			"code":    resp.StatusCode,
+			"message": serviceErr.Message,
+			"errors":  serviceErr.Errors,
+		}, serviceErr
 	}
 
 	var jsonResponse map[string]interface{}
@@ -158,6 +314,28 @@ func (s *Service) Response() (map[string]interface{}, error) {
 	return jsonResponse, nil
 }
 
+// publish sends an async call through the broker.
+func (s *Service) publish(ctx context.Context, method, uri string, headers map[string]string, body []byte) error {
+	id, err := helpers.NewUUID()
+	if err != nil {
+		return err
+	}
+
+	topic := s.BrokerTopic
+	if topic == "" {
+		topic = s.name()
+	}
+
+	return s.Broker.Publish(context.WithoutCancel(ctx), topic, broker.Message{
+		ID:          id,
+		Method:      method,
+		URI:         uri,
+		Headers:     headers,
+		Body:        body,
+		PublishedAt: time.Now(),
+	})
+}
+
 // isAsync checks if the URI is asynchronous.
 func (s *Service) isAsync(uri string) bool {
 	for _, asyncURI := range s.AsyncURIs {
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SIM-MBKM/mod-service/src/helpers"
+)
+
+func TestRequestErrorMessageLocalized(t *testing.T) {
+	t.Setenv("APP_KEY", "secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/laravel":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message": "The nim field is required.", "errors": {"nim": ["The nim field is required."]}}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	svc := NewService(server.URL, nil)
+	ctx := helpers.WithLocale(context.Background(), "id")
+
+	tests := []struct {
+		uri         string
+		wantMessage string
+	}{
+		{"laravel", "The nim field is required."},
+		{"plain", helpers.T(ctx, "service.request_failed", map[string]interface{}{"service": svc.name(), "status": http.StatusBadGateway})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			response, err := svc.RequestWithContext(ctx, http.MethodGet, tt.uri, nil, "")
+			var serviceErr *Error
+			if !errors.As(err, &serviceErr) {
+				t.Fatalf("error = %v, want *Error", err)
+			}
+			if response["message"] != tt.wantMessage || serviceErr.Message != tt.wantMessage {
+				t.Fatalf("message = %q, Error.Message = %q, want %q", response["message"], serviceErr.Message, tt.wantMessage)
+			}
+		})
+	}
+}